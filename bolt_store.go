@@ -0,0 +1,148 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltGraphBucket = []byte("simplegraph")
+
+// BoltGraph is a durable, single-file KVStore backed by bbolt. Keys are stored exactly as the hexastore indices
+// produce them, in a single bucket, so prefix scans come back in the same order as they would from FdbGraph.
+type BoltGraph struct {
+	db *bolt.DB
+}
+
+// OpenBoltGraph opens the graph file at path, creating it if it doesn't exist yet
+func OpenBoltGraph(path string, mode os.FileMode) (*BoltGraph, error) {
+	db, e := bolt.Open(path, mode, nil)
+
+	if e != nil {
+		return nil, e
+	}
+
+	e = db.Update(func(txn *bolt.Tx) error {
+		_, e := txn.CreateBucketIfNotExists(boltGraphBucket)
+		return e
+	})
+
+	if e != nil {
+		_ = db.Close()
+		return nil, e
+	}
+
+	return &BoltGraph{db: db}, nil
+}
+
+func (b *BoltGraph) Close() error {
+	return b.db.Close()
+}
+
+// Get streams every key with the given prefix from a single read transaction. bbolt can't grow its memory map
+// while a read transaction is open, so consumers shouldn't write to the same BoltGraph until the stream is drained.
+func (b *BoltGraph) Get(ctx context.Context, prefix []byte, outputStream chan<- []byte) error {
+	return b.ascend(ctx, prefix, func(key []byte) bool {
+		return bytes.HasPrefix(key, prefix)
+	}, outputStream)
+}
+
+// GetRange streams every key from begin up to end, with the same caveat as Get
+func (b *BoltGraph) GetRange(ctx context.Context, begin, end []byte, outputStream chan<- []byte) error {
+	return b.ascend(ctx, begin, func(key []byte) bool {
+		return bytes.Compare(key, end) < 0
+	}, outputStream)
+}
+
+// Scan streams keys like GetRange, but can stop at a limit and run backwards from end
+func (b *BoltGraph) Scan(ctx context.Context, begin, end []byte, options ScanOptions, outputStream chan<- []byte) error {
+	count := 0
+	within := func(key []byte) bool {
+		count++
+		return options.Limit == 0 || count <= options.Limit
+	}
+
+	if !options.Reverse {
+		return b.ascend(ctx, begin, func(key []byte) bool {
+			return bytes.Compare(key, end) < 0 && within(key)
+		}, outputStream)
+	}
+
+	defer close(outputStream)
+
+	return b.db.View(func(txn *bolt.Tx) error {
+		cursor := txn.Bucket(boltGraphBucket).Cursor()
+
+		// start from the last key before end
+		key, _ := cursor.Seek(end)
+		if key == nil {
+			key, _ = cursor.Last()
+		} else {
+			key, _ = cursor.Prev()
+		}
+
+		for ; key != nil && bytes.Compare(key, begin) >= 0 && within(key); key, _ = cursor.Prev() {
+			select {
+			case outputStream <- append([]byte(nil), key...):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return nil
+	})
+}
+
+// ascend streams keys from begin onwards for as long as they're within the scan
+func (b *BoltGraph) ascend(ctx context.Context, begin []byte, within func(key []byte) bool,
+	outputStream chan<- []byte) error {
+	defer close(outputStream)
+
+	return b.db.View(func(txn *bolt.Tx) error {
+		cursor := txn.Bucket(boltGraphBucket).Cursor()
+
+		for key, _ := cursor.Seek(begin); key != nil && within(key); key, _ = cursor.Next() {
+			// keys are only valid for the life of the transaction
+			select {
+			case outputStream <- append([]byte(nil), key...):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return nil
+	})
+}
+
+// Put writes all keys in one transaction, which bbolt fsyncs before returning, so a crash leaves either all or none
+func (b *BoltGraph) Put(keys ...[]byte) error {
+	emptyByte := make([]byte, 0)
+
+	return b.db.Update(func(txn *bolt.Tx) error {
+		bucket := txn.Bucket(boltGraphBucket)
+
+		for _, key := range keys {
+			if e := bucket.Put(key, emptyByte); e != nil {
+				return e
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BoltGraph) Delete(keys ...[]byte) error {
+	return b.db.Update(func(txn *bolt.Tx) error {
+		bucket := txn.Bucket(boltGraphBucket)
+
+		for _, key := range keys {
+			if e := bucket.Delete(key); e != nil {
+				return e
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package simplegraph
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openTestBoltGraph(t *testing.T) (*BoltGraph, string) {
+	dir, e := ioutil.TempDir("", "simplegraph")
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	path := filepath.Join(dir, "graph.db")
+	store, e := OpenBoltGraph(path, 0600)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return store, dir
+}
+
+func TestBoltGraph_GetRangeStreamingAnd(t *testing.T) {
+	store, dir := openTestBoltGraph(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	testGetRangeStreamingAnd(t, store)
+}
+
+func TestBoltGraph_GetRange(t *testing.T) {
+	store, dir := openTestBoltGraph(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	testStoreGetRange(t, store)
+}
+
+func TestBoltGraph_Scan(t *testing.T) {
+	store, dir := openTestBoltGraph(t)
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	testStoreScan(t, store)
+}
+
+func TestBoltGraph_Persists(t *testing.T) {
+	store, dir := openTestBoltGraph(t)
+	defer os.RemoveAll(dir)
+
+	edge := NewEdge([]byte("Kyrie Irving"), []byte("played for"), []byte("Cleveland"))
+
+	if e := NewSimpleGraph(store).AddEdges([]Edge{edge}); e != nil {
+		t.Fatal(e)
+	}
+
+	_ = store.Close()
+
+	store, e := OpenBoltGraph(filepath.Join(dir, "graph.db"), 0600)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer store.Close()
+
+	edges, _ := NewSimpleGraph(store).GetEdges(context.Background(), NewQuery().WithSubject([]byte("Kyrie Irving")))
+
+	var got []*Edge
+	for edge := range edges.Edges() {
+		got = append(got, edge)
+	}
+
+	if !reflect.DeepEqual(got, []*Edge{&edge}) {
+		t.Fatalf("got %v after reopening, want %v", got, edge)
+	}
+
+	for _, index := range Indices {
+		_ = store.Delete(index.toBytes(&edge))
+	}
+
+	edges, _ = NewSimpleGraph(store).GetEdges(context.Background(), NewQuery().WithSubject([]byte("Kyrie Irving")))
+
+	for edge := range edges.Edges() {
+		t.Fatalf("got %v after deleting", edge)
+	}
+}
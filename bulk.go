@@ -0,0 +1,207 @@
+package simplegraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchBytes  = 1 << 20
+	defaultParallelism = 4
+	defaultRetries     = 3
+
+	// mutationOverhead is roughly what FDB counts against the transaction size for each key besides its bytes
+	mutationOverhead = 32
+	retryPause       = 100 * time.Millisecond
+)
+
+// BulkOptions tune AddEdgesInBatches. Zero values use the defaults.
+type BulkOptions struct {
+	// BatchBytes caps the size of the keys written in one call to the store, which for FoundationDB is one
+	// transaction. FDB rejects transactions over 10MB, and works best with ones under the default of 1MB.
+	BatchBytes int
+
+	// Parallelism is the number of batches written at once, 4 by default
+	Parallelism int
+
+	// Retries is the number of times a failed batch is tried again, after a growing pause. It's 3 by default, and a
+	// negative number disables retries.
+	Retries int
+
+	// Progress is called after each batch is written or given up on, one call at a time
+	Progress func(BulkProgress)
+}
+
+type BulkProgress struct {
+	Batches, BatchesWritten, BatchesFailed int
+	Edges, EdgesWritten                    int
+}
+
+// BatchError reports a batch of a bulk write that still failed after its retries. Writing an edge twice is harmless,
+// so its edges can be passed straight back to AddEdgesInBatches.
+type BatchError struct {
+	Batch int
+	Edges []Edge
+	Err   error
+}
+
+func (be *BatchError) Error() string {
+	return fmt.Sprintf("batch %v of %v edges: %v", be.Batch, len(be.Edges), be.Err)
+}
+
+func (be *BatchError) Unwrap() error {
+	return be.Err
+}
+
+// BulkError lists the batches of a bulk write that weren't written, in batch order. Every other batch was.
+type BulkError struct {
+	Batches []*BatchError
+}
+
+func (be *BulkError) Error() string {
+	return fmt.Sprintf("%v batches failed, the first with %v", len(be.Batches), be.Batches[0])
+}
+
+type edgeBatch struct {
+	number int
+	edges  []Edge
+	keys   [][]byte
+}
+
+// AddEdgesInBatches writes edges too many for one transaction, by splitting them into batches of at most
+// options.BatchBytes and writing several batches at once. Each edge is written to all six indices in the same batch,
+// but the write as a whole isn't atomic: if some batches fail, the rest are still written, and the failures are
+// returned as a *BulkError. Use AddEdges to write everything or nothing.
+func (graph *SimpleGraph) AddEdgesInBatches(ctx context.Context, edges []Edge, options BulkOptions) error {
+	if e := validateEdges(edges); e != nil {
+		return e
+	}
+
+	if options.BatchBytes <= 0 {
+		options.BatchBytes = defaultBatchBytes
+	}
+
+	if options.Parallelism <= 0 {
+		options.Parallelism = defaultParallelism
+	}
+
+	if options.Retries == 0 {
+		options.Retries = defaultRetries
+	}
+
+	batches := splitBatches(edges, options.BatchBytes)
+	progress := BulkProgress{ Batches: len(batches), Edges: len(edges) }
+	var failed []*BatchError
+	var mu sync.Mutex
+
+	done := func(batch *edgeBatch, e error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if e != nil {
+			failed = append(failed, &BatchError{ Batch: batch.number, Edges: batch.edges, Err: e })
+			progress.BatchesFailed++
+		} else {
+			progress.BatchesWritten++
+			progress.EdgesWritten += len(batch.edges)
+		}
+
+		if options.Progress != nil {
+			options.Progress(progress)
+		}
+	}
+
+	work := make(chan *edgeBatch)
+	var workers sync.WaitGroup
+
+	for i := 0; i < options.Parallelism; i++ {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for batch := range work {
+				done(batch, graph.putBatch(ctx, batch, options.Retries))
+			}
+		}()
+	}
+
+	for _, batch := range batches {
+		// once cancelled, the batches that are left fail without being tried
+		if ctx.Err() != nil {
+			done(batch, ctx.Err())
+			continue
+		}
+
+		select {
+		case work <- batch:
+		case <-ctx.Done():
+			done(batch, ctx.Err())
+		}
+	}
+
+	close(work)
+	workers.Wait()
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].Batch < failed[j].Batch
+	})
+
+	return &BulkError{ Batches: failed }
+}
+
+func (graph *SimpleGraph) putBatch(ctx context.Context, batch *edgeBatch, retries int) error {
+	e := graph.kvstore.Put(batch.keys...)
+
+	for attempt := 0; e != nil && attempt < retries; attempt++ {
+		select {
+		case <-time.After(retryPause << uint(attempt)):
+		case <-ctx.Done():
+			return e
+		}
+
+		e = graph.kvstore.Put(batch.keys...)
+	}
+
+	return e
+}
+
+// splitBatches groups edges, in order, into batches whose keys fit in batchBytes. An edge too big for a batch on its
+// own gets one to itself.
+func splitBatches(edges []Edge, batchBytes int) []*edgeBatch {
+	var batches []*edgeBatch
+	current := &edgeBatch{}
+	size := 0
+
+	for i := range edges {
+		keys := indexKeys(edges[i:i + 1])
+		edgeSize := 0
+
+		for _, key := range keys {
+			edgeSize += len(key) + mutationOverhead
+		}
+
+		if size + edgeSize > batchBytes && len(current.edges) > 0 {
+			batches = append(batches, current)
+			current = &edgeBatch{ number: len(batches) }
+			size = 0
+		}
+
+		current.edges = append(current.edges, edges[i])
+		current.keys = append(current.keys, keys...)
+		size += edgeSize
+	}
+
+	if len(current.edges) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
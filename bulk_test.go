@@ -0,0 +1,176 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// flakyStore fails each write that includes a key containing poison, until it has failed failures times.
+// Negative failures fail forever.
+type flakyStore struct {
+	*MemoryGraph
+
+	poison   []byte
+	failures int
+
+	mu    sync.Mutex
+	puts  int
+	sizes []int
+}
+
+func (f *flakyStore) Put(keys ...[]byte) error {
+	f.mu.Lock()
+	f.puts++
+
+	size := 0
+	for _, key := range keys {
+		size += len(key) + mutationOverhead
+
+		if f.poison != nil && bytes.Contains(key, f.poison) && f.failures != 0 {
+			f.failures--
+			f.mu.Unlock()
+			return errors.New("transaction failed")
+		}
+	}
+
+	f.sizes = append(f.sizes, size)
+	f.mu.Unlock()
+
+	return f.MemoryGraph.Put(keys...)
+}
+
+func bulkEdges(n int) []Edge {
+	edges := make([]Edge, n)
+
+	for i := range edges {
+		edges[i] = NewEdge([]byte("player"), []byte("scored"), int64(i))
+	}
+
+	return edges
+}
+
+func countEdges(t *testing.T, graph *SimpleGraph) int {
+	stream, e := graph.GetEdges(context.Background(), NewQuery().WithPredicate([]byte("scored")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	count := 0
+	for range stream.Edges() {
+		count++
+	}
+
+	if e := stream.Err(); e != nil {
+		t.Fatal(e)
+	}
+
+	return count
+}
+
+func TestSimpleGraph_AddEdgesInBatches(t *testing.T) {
+	store := &flakyStore{ MemoryGraph: NewMemoryGraph() }
+	graph := NewSimpleGraph(store)
+	edges := bulkEdges(1000)
+
+	var progress []BulkProgress
+	e := graph.AddEdgesInBatches(context.Background(), edges, BulkOptions{
+		BatchBytes: 16 << 10,
+		Parallelism: 3,
+		Progress: func(p BulkProgress) {
+			progress = append(progress, p)
+		},
+	})
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if got := countEdges(t, graph); got != len(edges) {
+		t.Errorf("got %v edges, want %v", got, len(edges))
+	}
+
+	if store.puts < 2 {
+		t.Errorf("wrote everything in %v batches", store.puts)
+	}
+
+	for _, size := range store.sizes {
+		if size > 16 << 10 {
+			t.Errorf("wrote a batch of %v bytes", size)
+		}
+	}
+
+	if len(progress) != store.puts {
+		t.Errorf("got %v progress reports for %v batches", len(progress), store.puts)
+	}
+
+	want := BulkProgress{ Batches: store.puts, BatchesWritten: store.puts, Edges: 1000, EdgesWritten: 1000 }
+	if last := progress[len(progress) - 1]; !reflect.DeepEqual(last, want) {
+		t.Errorf("got final progress %+v, want %+v", last, want)
+	}
+}
+
+func TestSimpleGraph_AddEdgesInBatchesRetries(t *testing.T) {
+	poisoned := Indices["spo"].toBytes(&Edge{ subject: []byte("player"), predicate: []byte("scored"), object: int64(500) })
+
+	store := &flakyStore{ MemoryGraph: NewMemoryGraph(), poison: poisoned, failures: 2 }
+	graph := NewSimpleGraph(store)
+
+	if e := graph.AddEdgesInBatches(context.Background(), bulkEdges(1000), BulkOptions{ BatchBytes: 16 << 10 }); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := countEdges(t, graph); got != 1000 {
+		t.Errorf("got %v edges after retrying, want 1000", got)
+	}
+
+	store = &flakyStore{ MemoryGraph: NewMemoryGraph(), poison: poisoned, failures: -1 }
+	graph = NewSimpleGraph(store)
+	e := graph.AddEdgesInBatches(context.Background(), bulkEdges(1000), BulkOptions{ BatchBytes: 16 << 10, Retries: 1 })
+
+	var bulkError *BulkError
+	if !errors.As(e, &bulkError) || len(bulkError.Batches) != 1 {
+		t.Fatalf("got %v, want a single failed batch", e)
+	}
+
+	failed := bulkError.Batches[0]
+	if got := countEdges(t, graph); got != 1000 - len(failed.Edges) {
+		t.Errorf("got %v edges, want all but the %v in the failed batch", got, len(failed.Edges))
+	}
+
+	// the failed batch's edges can be written again once the store recovers
+	store.poison = nil
+	if e := graph.AddEdgesInBatches(context.Background(), failed.Edges, BulkOptions{}); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := countEdges(t, graph); got != 1000 {
+		t.Errorf("got %v edges after writing the failed batch, want 1000", got)
+	}
+}
+
+func TestSimpleGraph_AddEdgesInBatchesCancelled(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := graph.AddEdgesInBatches(ctx, bulkEdges(100), BulkOptions{ BatchBytes: 4 << 10 })
+
+	var bulkError *BulkError
+	if !errors.As(e, &bulkError) {
+		t.Fatalf("got %v, want a BulkError", e)
+	}
+
+	unwritten := 0
+	for _, batch := range bulkError.Batches {
+		unwritten += len(batch.Edges)
+	}
+
+	if written := countEdges(t, graph); written + unwritten != 100 {
+		t.Errorf("%v edges were written and %v reported unwritten, want 100 in all", written, unwritten)
+	}
+}
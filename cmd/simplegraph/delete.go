@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/pH14/simplegraph"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "delete",
+		summary: "delete the edges matching a pattern, e.g. '\"Paul Pierce\" _ _'",
+		run:     runDelete,
+	})
+}
+
+func runDelete(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	yes := flags.Bool("yes", false, "delete the edges. Without it, delete only counts them.")
+
+	if e := flags.Parse(args); e != nil {
+		return e
+	}
+
+	patterns, e := parsePatterns(flags)
+
+	if e != nil {
+		return e
+	}
+
+	if len(patterns) != 1 {
+		return fmt.Errorf("expected one pattern, got %v", len(patterns))
+	}
+
+	graph, closeStore, e := store.open()
+
+	if e != nil {
+		return e
+	}
+
+	defer closeStore()
+
+	ctx := context.Background()
+
+	if *yes {
+		count, e := graph.DeleteMatching(ctx, patterns[0])
+
+		if e != nil {
+			return e
+		}
+
+		fmt.Fprintf(stdout, "deleted %v edges\n", count)
+		return nil
+	}
+
+	count, e := countEdges(ctx, graph, patterns[0])
+
+	if e != nil {
+		return e
+	}
+
+	fmt.Fprintf(stdout, "%v edges match %v, run again with -yes to delete them\n", count, patterns[0])
+	return nil
+}
+
+func countEdges(ctx context.Context, graph *simplegraph.SimpleGraph, query simplegraph.Query) (int, error) {
+	stream, e := graph.GetEdges(ctx, query)
+
+	if e != nil {
+		return 0, e
+	}
+
+	count := 0
+	for range stream.Edges() {
+		count++
+	}
+
+	return count, stream.Err()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/pH14/simplegraph"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "explain",
+		summary: "print the plan query would run for triple patterns",
+		run:     runExplain,
+	})
+}
+
+func runExplain(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	analyze := flags.Bool("analyze", false, "run the query, and print what each step of the plan did")
+	asJSON := flags.Bool("json", false, "print the plan as JSON")
+
+	if e := flags.Parse(args); e != nil {
+		return e
+	}
+
+	patterns, e := parsePatterns(flags)
+
+	if e != nil {
+		return e
+	}
+
+	graph, closeStore, e := store.open()
+
+	if e != nil {
+		return e
+	}
+
+	defer closeStore()
+
+	plan, e := explain(context.Background(), graph, patterns, *analyze)
+
+	if e != nil {
+		return e
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(plan)
+	}
+
+	_, e = fmt.Fprint(stdout, plan)
+	return e
+}
+
+func explain(ctx context.Context, graph *simplegraph.SimpleGraph, patterns []simplegraph.Query, analyze bool) (*simplegraph.PlanNode, error) {
+	if analyze {
+		return graph.ExplainAnalyze(ctx, patterns...)
+	}
+
+	return graph.Explain(patterns...)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pH14/simplegraph"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "export",
+		summary: "write the edges matching a pattern, or every edge, to stdout",
+		run:     runExport,
+	})
+}
+
+func runExport(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	format := flags.String("format", "ntriples", "the output format: ntriples, turtle, jsonl, jsonld, dot or graphml")
+	prefixes := prefixFlag{}
+	flags.Var(prefixes, "prefix", "abbreviate IRIs in turtle and jsonld, as name=iri. Can be repeated.")
+	maxEdges := flags.Int("max-edges", 0, "draw at most this many edges in dot and graphml, if more than 0")
+
+	if e := flags.Parse(args); e != nil {
+		return e
+	}
+
+	patterns := []simplegraph.Query{ simplegraph.NewQuery() }
+
+	if flags.NArg() > 0 {
+		var e error
+		if patterns, e = parsePatterns(flags); e != nil {
+			return e
+		}
+	}
+
+	if len(patterns) > 1 && *format != "dot" && *format != "graphml" {
+		return fmt.Errorf("only dot and graphml can export more than one pattern")
+	}
+
+	graph, closeStore, e := store.open()
+
+	if e != nil {
+		return e
+	}
+
+	defer closeStore()
+
+	ctx := context.Background()
+	options := simplegraph.DiagramOptions{ MaxEdges: *maxEdges }
+	var count int
+
+	switch *format {
+	case "ntriples":
+		count, e = graph.ExportNTriples(ctx, stdout, patterns[0])
+	case "turtle":
+		count, e = graph.ExportTurtle(ctx, stdout, patterns[0], prefixes)
+	case "jsonl":
+		encoder := simplegraph.NewJSONLinesEncoder(stdout)
+		count, e = exportEdges(ctx, graph, patterns[0], encoder.EncodeEdge, encoder.Flush)
+	case "jsonld":
+		encoder := simplegraph.NewJSONLDEncoder(stdout, prefixes)
+		count, e = exportEdges(ctx, graph, patterns[0], encoder.EncodeEdge, encoder.Close)
+	case "dot":
+		count, e = graph.ExportDOT(ctx, stdout, options, patterns...)
+	case "graphml":
+		count, e = graph.ExportGraphML(ctx, stdout, options, patterns...)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if e != nil {
+		return e
+	}
+
+	// stdout holds the export, so the count goes to stderr
+	fmt.Fprintf(stderr, "exported %v edges\n", count)
+	return nil
+}
+
+// exportEdges encodes each edge matching the query, then finishes the encoding
+func exportEdges(ctx context.Context, graph *simplegraph.SimpleGraph, query simplegraph.Query,
+	encode func(edge simplegraph.Edge) error, finish func() error) (int, error) {
+
+	stream, e := graph.GetEdges(ctx, query)
+
+	if e != nil {
+		return 0, e
+	}
+
+	defer stream.Close()
+
+	count := 0
+	for edge := range stream.Edges() {
+		if e := encode(*edge); e != nil {
+			return count, e
+		}
+
+		count++
+	}
+
+	if e := stream.Err(); e != nil {
+		return count, e
+	}
+
+	return count, finish()
+}
+
+// prefixFlag collects name=iri flags
+type prefixFlag map[string]string
+
+func (pf prefixFlag) String() string {
+	var pairs []string
+
+	for name, iri := range pf {
+		pairs = append(pairs, name + "=" + iri)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (pf prefixFlag) Set(value string) error {
+	name, iri, ok := strings.Cut(value, "=")
+
+	if !ok {
+		return fmt.Errorf("expected name=iri, got %q", value)
+	}
+
+	pf[name] = iri
+	return nil
+}
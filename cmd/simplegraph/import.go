@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pH14/simplegraph"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "import",
+		summary: "load triples from a file, or stdin",
+		run:     runImport,
+	})
+}
+
+func runImport(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	format := flags.String("format", "csv", "the file's format: csv, tsv, ntriples or turtle")
+	delimiter := flags.String("delimiter", "", "the field delimiter, if not the format's")
+	quote := flags.String("quote", `"`, "the quote character")
+	noQuoting := flags.Bool("no-quoting", false, "read quote characters like any other")
+	keepSpace := flags.Bool("keep-space", false, "keep the space around fields")
+	header := flags.Bool("header", false, "skip the first line, and let -columns use its names")
+	columns := flags.String("columns", "", "the subject, predicate and object columns, by header name or zero based position, e.g. 2,0,1")
+	batchSize := flags.Int("batch", 1000, "the number of edges to write at once")
+	dryRun := flags.Bool("dry-run", false, "check the file and report malformed lines, without writing anything. RDF formats stop at the first.")
+	analyze := flags.Bool("analyze", true, "refresh the statistics the query planner uses once the import is done")
+
+	e := flags.Parse(args)
+
+	if e != nil {
+		return e
+	}
+
+	options := simplegraph.CSVOptions{
+		NoQuoting: *noQuoting,
+		KeepSpace: *keepSpace,
+		Header:    *header,
+		BatchSize: *batchSize,
+		DryRun:    *dryRun,
+	}
+
+	switch *format {
+	case "csv":
+		options.Delimiter = ','
+	case "tsv":
+		options.Delimiter = '\t'
+	case "ntriples", "turtle":
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	if *delimiter != "" {
+		if options.Delimiter, e = singleRune("delimiter", *delimiter); e != nil {
+			return e
+		}
+	}
+
+	if !*noQuoting {
+		if options.Quote, e = singleRune("quote", *quote); e != nil {
+			return e
+		}
+	}
+
+	if *columns != "" {
+		options.Columns = strings.Split(*columns, ",")
+	}
+
+	input, e := openInput(flags, stdin)
+
+	if e != nil {
+		return e
+	}
+
+	defer input.Close()
+
+	// a dry run never writes, so it doesn't need a store, and this one stays empty
+	graph := simplegraph.NewSimpleGraph(simplegraph.NewMemoryGraph())
+
+	if !*dryRun {
+		var closeStore func() error
+		graph, closeStore, e = store.open()
+
+		if e != nil {
+			return e
+		}
+
+		defer closeStore()
+	}
+
+	var report *simplegraph.ImportReport
+
+	rdfOptions := simplegraph.RDFOptions{ BatchSize: *batchSize, DryRun: *dryRun }
+
+	switch *format {
+	case "ntriples":
+		report, e = graph.ImportNTriples(context.Background(), input, rdfOptions)
+	case "turtle":
+		report, e = graph.ImportTurtle(context.Background(), input, rdfOptions)
+	default:
+		report, e = graph.ImportCSV(context.Background(), input, options)
+	}
+
+	if e == nil && !*dryRun && *analyze {
+		_, e = graph.Analyze(context.Background())
+	}
+
+	if report != nil {
+		for _, malformed := range report.Malformed {
+			fmt.Fprintf(stdout, "%v\n\t%v\n", malformed, malformed.Text)
+		}
+
+		verb := "imported"
+		if *dryRun {
+			verb = "checked"
+		}
+
+		fmt.Fprintf(stdout, "%v %v edges from %v lines, %v malformed\n", verb, report.Edges, report.Lines, len(report.Malformed))
+
+		if e == nil && len(report.Malformed) > 0 {
+			return fmt.Errorf("%v malformed lines", len(report.Malformed))
+		}
+	}
+
+	return e
+}
+
+// singleRune reads a flag naming one character, allowing the escape \t
+func singleRune(name, value string) (rune, error) {
+	switch value {
+	case `\t`:
+		return '\t', nil
+	case "":
+		return 0, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(value)
+
+	if size != len(value) || r == utf8.RuneError {
+		return 0, fmt.Errorf("-%v must be a single character, not %q", name, value)
+	}
+
+	return r, nil
+}
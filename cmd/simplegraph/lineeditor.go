@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted is returned by readLine when the line is abandoned with ctrl-c
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the REPL's input a line at a time
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// completer suggests completions for the text before the cursor: it returns the index of the rune the completions
+// replace the text from, and the completions
+type completer func(before []rune) (start int, completions []string)
+
+// lineEditor edits lines in a terminal in raw mode. It understands the arrow keys, home and end, ctrl-a, ctrl-e,
+// ctrl-u and ctrl-w, steps through earlier lines with up and down, and completes with tab. A second tab lists the
+// completions when there's more than one.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete completer
+
+	prompt string
+	line   []rune
+	cursor int
+}
+
+func newLineEditor(in io.Reader, out io.Writer, history []string, complete completer) *lineEditor {
+	return &lineEditor{ in: bufio.NewReader(in), out: out, history: history, complete: complete }
+}
+
+func (le *lineEditor) readLine(prompt string) (string, error) {
+	le.prompt, le.line, le.cursor = prompt, nil, 0
+	le.redraw()
+
+	// position is how far back through the history up has gone, and draft is the line being written before it did
+	position := len(le.history)
+	var draft []rune
+	listed := false
+
+	for {
+		r, _, e := le.in.ReadRune()
+
+		if e != nil {
+			return "", e
+		}
+
+		tabbed := false
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(le.out, "\r\n")
+			line := string(le.line)
+
+			if strings.TrimSpace(line) != "" && (len(le.history) == 0 || le.history[len(le.history) - 1] != line) {
+				le.history = append(le.history, line)
+
+				if len(le.history) > historySize {
+					le.history = le.history[len(le.history) - historySize:]
+				}
+			}
+
+			return line, nil
+		case 3: // ctrl-c
+			fmt.Fprint(le.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // ctrl-d ends the input on an empty line, and deletes otherwise
+			if len(le.line) == 0 {
+				fmt.Fprint(le.out, "\r\n")
+				return "", io.EOF
+			}
+
+			le.delete(le.cursor)
+		case 1: // ctrl-a
+			le.cursor = 0
+		case 5: // ctrl-e
+			le.cursor = len(le.line)
+		case 21: // ctrl-u
+			le.line, le.cursor = append([]rune{}, le.line[le.cursor:]...), 0
+		case 23: // ctrl-w
+			start := le.cursor
+			for start > 0 && unicode.IsSpace(le.line[start - 1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(le.line[start - 1]) {
+				start--
+			}
+
+			le.line, le.cursor = append(le.line[:start], le.line[le.cursor:]...), start
+		case 8, 127: // backspace
+			if le.cursor > 0 {
+				le.cursor--
+				le.delete(le.cursor)
+			}
+		case '\t':
+			tabbed = true
+			le.tab(listed)
+		case 27:
+			switch le.escape() {
+			case "A":
+				if position > 0 {
+					if position == len(le.history) {
+						draft = le.line
+					}
+
+					position--
+					le.line = []rune(le.history[position])
+					le.cursor = len(le.line)
+				}
+			case "B":
+				if position < len(le.history) {
+					position++
+
+					if position == len(le.history) {
+						le.line = draft
+					} else {
+						le.line = []rune(le.history[position])
+					}
+
+					le.cursor = len(le.line)
+				}
+			case "C":
+				if le.cursor < len(le.line) {
+					le.cursor++
+				}
+			case "D":
+				if le.cursor > 0 {
+					le.cursor--
+				}
+			case "H", "1~":
+				le.cursor = 0
+			case "F", "4~":
+				le.cursor = len(le.line)
+			case "3~":
+				le.delete(le.cursor)
+			}
+		default:
+			if unicode.IsPrint(r) {
+				le.insert([]rune{ r })
+			}
+		}
+
+		listed = tabbed
+		le.redraw()
+	}
+}
+
+// escape reads the rest of an escape sequence, e.g. [A for up, and returns what follows the [ or O
+func (le *lineEditor) escape() string {
+	r, _, e := le.in.ReadRune()
+
+	if e != nil || (r != '[' && r != 'O') {
+		return ""
+	}
+
+	var sequence []rune
+	for {
+		r, _, e := le.in.ReadRune()
+
+		if e != nil {
+			return ""
+		}
+
+		sequence = append(sequence, r)
+
+		if r < '0' || r > '9' {
+			return string(sequence)
+		}
+	}
+}
+
+func (le *lineEditor) insert(runes []rune) {
+	line := append([]rune{}, le.line[:le.cursor]...)
+	line = append(line, runes...)
+	le.line = append(line, le.line[le.cursor:]...)
+	le.cursor += len(runes)
+}
+
+func (le *lineEditor) delete(at int) {
+	if at < len(le.line) {
+		le.line = append(le.line[:at], le.line[at + 1:]...)
+	}
+}
+
+// tab completes as much as the completions share, and lists them if that's nothing new and list is set
+func (le *lineEditor) tab(list bool) {
+	if le.complete == nil {
+		return
+	}
+
+	start, completions := le.complete(le.line[:le.cursor])
+
+	if len(completions) == 0 {
+		return
+	}
+
+	shared := []rune(completions[0])
+	for _, completion := range completions[1:] {
+		shared = commonPrefix(shared, []rune(completion))
+	}
+
+	typed := le.line[start:le.cursor]
+
+	if len(shared) > len(typed) {
+		le.line = append(append([]rune{}, le.line[:start]...), le.line[le.cursor:]...)
+		le.cursor = start
+		le.insert(shared)
+		return
+	}
+
+	if list && len(completions) > 1 {
+		fmt.Fprintf(le.out, "\r\n%v\r\n", strings.Join(completions, "  "))
+	}
+}
+
+func commonPrefix(a, b []rune) []rune {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return a[:i]
+}
+
+// redraw rewrites the line from the start and puts the cursor back where it belongs
+func (le *lineEditor) redraw() {
+	fmt.Fprintf(le.out, "\r%v%v\x1b[K", le.prompt, string(le.line))
+
+	if back := len(le.line) - le.cursor; back > 0 {
+		fmt.Fprintf(le.out, "\x1b[%vD", back)
+	}
+}
+
+// plainReader reads lines from anything that isn't a terminal, without prompts
+type plainReader struct {
+	scanner *bufio.Scanner
+}
+
+func (pr *plainReader) readLine(prompt string) (string, error) {
+	if !pr.scanner.Scan() {
+		if e := pr.scanner.Err(); e != nil {
+			return "", e
+		}
+
+		return "", io.EOF
+	}
+
+	return pr.scanner.Text(), nil
+}
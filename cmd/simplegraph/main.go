@@ -0,0 +1,126 @@
+// Command simplegraph imports, queries, exports and deletes the triples of a simplegraph, kept in FoundationDB or
+// in a local file, and prints its statistics and query plans. Queries are triple patterns, run once or from the repl, e.g.
+//
+//	simplegraph query -cluster-file fdb.cluster '?x "played for" Celtics . ?x "played for" Timberwolves'
+//
+// Usage:
+//
+//	simplegraph <command> [flags] [arguments]
+//
+// Run a command with -h for its flags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/pH14/simplegraph"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+var commands []*command
+
+func main() {
+	e := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+
+	if e == flag.ErrHelp {
+		os.Exit(2)
+	} else if e != nil {
+		fmt.Fprintln(os.Stderr, "simplegraph:", e)
+		os.Exit(1)
+	}
+}
+
+// run runs the command named by the first argument. Commands write what they were asked for to stdout, and notes
+// about it, like usage, to stderr.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.name == args[0] {
+				return c.run(args[1:], stdin, stdout, stderr)
+			}
+		}
+	}
+
+	fmt.Fprintln(stderr, "usage: simplegraph <command> [flags] [arguments]")
+	fmt.Fprintln(stderr, "\ncommands:")
+
+	for _, c := range commands {
+		fmt.Fprintf(stderr, "  %-10v %v\n", c.name, c.summary)
+	}
+
+	return fmt.Errorf("expected a command")
+}
+
+// storeFlags choose where the graph is kept: a local file if -db is set, otherwise FoundationDB
+type storeFlags struct {
+	path        string
+	clusterFile string
+}
+
+func (sf *storeFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&sf.path, "db", "", "keep the graph in this local file, creating it if needed, instead of FoundationDB")
+	flags.StringVar(&sf.clusterFile, "cluster-file", "", "the FoundationDB cluster file, if not the default")
+}
+
+// open returns the graph, with the statistics its last analysis saved, and a function that releases it
+func (sf *storeFlags) open() (*simplegraph.SimpleGraph, func() error, error) {
+	var store simplegraph.KVStore
+	closeStore := func() error { return nil }
+
+	if sf.path != "" {
+		bolt, e := simplegraph.OpenBoltGraph(sf.path, 0600)
+
+		if e != nil {
+			return nil, nil, e
+		}
+
+		store, closeStore = bolt, bolt.Close
+	} else {
+		if e := fdb.APIVersion(600); e != nil {
+			return nil, nil, e
+		}
+
+		database, e := fdb.OpenDatabase(sf.clusterFile)
+
+		if e != nil {
+			return nil, nil, e
+		}
+
+		store = simplegraph.NewFdbGraph(&database)
+	}
+
+	graph := simplegraph.NewSimpleGraph(store)
+
+	if _, e := graph.LoadStatistics(context.Background()); e != nil {
+		_ = closeStore()
+		return nil, nil, e
+	}
+
+	return graph, closeStore, nil
+}
+
+// openInput opens the file named by the command's only argument, or stdin if there isn't one or it's -
+func openInput(flags *flag.FlagSet, stdin io.Reader) (io.ReadCloser, error) {
+	switch flags.NArg() {
+	case 0:
+		return io.NopCloser(stdin), nil
+	case 1:
+		if flags.Arg(0) == "-" {
+			return io.NopCloser(stdin), nil
+		}
+
+		return os.Open(flags.Arg(0))
+	default:
+		return nil, fmt.Errorf("expected one file, got %v", flags.Args())
+	}
+}
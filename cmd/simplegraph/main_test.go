@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "graph.db")
+	var stdout bytes.Buffer
+
+	if e := run([]string{ "import", "-db", db, "../../testdata/nba.csv" }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); got != "imported 12 edges from 12 lines, 0 malformed\n" {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+	turtle := "@prefix nba: <http://nba.example/> .\nnba:pierce nba:playedFor nba:Celtics, nba:Nets .\n"
+
+	if e := run([]string{ "import", "-db", db, "-format", "turtle" }, strings.NewReader(turtle), &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); got != "imported 2 edges from 2 lines, 0 malformed\n" {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+	e := run([]string{ "import", "-format", "tsv", "-dry-run" }, strings.NewReader("a\tb\tc\na\tb\n"), &stdout, io.Discard)
+
+	if e == nil || !strings.Contains(stdout.String(), "line 2: expected at least 3 fields, found 2") {
+		t.Errorf("got %v, %q", e, stdout.String())
+	}
+
+	stdout.Reset()
+	e = run([]string{ "import", "-format", "ntriples", "-dry-run" }, strings.NewReader("<a> <b> <c> .\n<a> <b>\n"), &stdout, io.Discard)
+
+	if e == nil || !strings.HasSuffix(stdout.String(), "checked 1 edges from 2 lines, 1 malformed\n") {
+		t.Errorf("got %v, %q", e, stdout.String())
+	}
+
+	for _, flag := range []string{ "-delimiter=||", "-quote=''" } {
+		e = run([]string{ "import", "-dry-run", flag }, strings.NewReader("a||b||c\n"), io.Discard, io.Discard)
+
+		if e == nil || !strings.Contains(e.Error(), "must be a single character") {
+			t.Errorf("%v: got %v", flag, e)
+		}
+	}
+
+	if e := run([]string{ "import", "-dry-run", `-delimiter=\t` }, strings.NewReader("a\tb\tc\n"), io.Discard, io.Discard); e != nil {
+		t.Errorf("got %v for a tab delimiter", e)
+	}
+}
+
+// importNBA loads testdata/nba.csv into a new local graph, and returns its path
+func importNBA(t *testing.T) string {
+	db := filepath.Join(t.TempDir(), "graph.db")
+
+	if e := run([]string{ "import", "-db", db, "../../testdata/nba.csv" }, nil, io.Discard, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	return db
+}
+
+func TestCommands(t *testing.T) {
+	db := importNBA(t)
+	both := `?x "former player" Celtics . ?x "former player" Timberwolves`
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "query table",
+			args: []string{ "query", "-db", db, both },
+			want: "x\nAl Jefferson\nKevin Garnett\n",
+		},
+		{
+			name: "query csv",
+			args: []string{ "query", "-db", db, "-format", "csv", "-limit", "1", `?x "former player" ?team` },
+			want: "x,team\nAl Jefferson,Celtics\n",
+		},
+		{
+			name: "query json",
+			args: []string{ "query", "-db", db, "-format", "json", "-limit", "1", both },
+			// the import's statistics have the planner read the rarer Timberwolves first
+			want: `{"bindings":{"x":"Al Jefferson"},"edges":[` +
+				`{"s":"Al Jefferson","p":"former player","o":"Timberwolves"},` +
+				`{"s":"Al Jefferson","p":"former player","o":"Celtics"}]}` + "\n",
+		},
+		{
+			name: "export",
+			args: []string{ "export", "-db", db, "-format", "jsonl", `"Paul Pierce" _ _` },
+			want: `{"s":"Paul Pierce","p":"former player","o":"Celtics"}` + "\n",
+		},
+		{
+			name: "delete counts without -yes",
+			args: []string{ "delete", "-db", db, `"Paul Pierce" _ _` },
+			want: "1 edges match (\"Paul Pierce\", _, _), run again with -yes to delete them\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+
+			if e := run(tt.args, nil, &stdout, io.Discard); e != nil {
+				t.Fatal(e)
+			}
+
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	db := importNBA(t)
+	var stdout bytes.Buffer
+
+	if e := run([]string{ "delete", "-db", db, "-yes", `?x "former player" Celtics` }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); got != "deleted 3 edges\n" {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+
+	if e := run([]string{ "query", "-db", db, `?x _ Celtics` }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); got != "x\nAl Horford\nDoc Rivers\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestStatsAndExplain(t *testing.T) {
+	db := importNBA(t)
+	var stdout bytes.Buffer
+
+	// the import analyzed the graph, and explain plans with what it saved
+	if e := run([]string{ "explain", "-db", db, `?x "former player" Celtics` }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); !strings.HasPrefix(got, "estimates from the graph's statistics\n") {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+	unanalyzed := filepath.Join(t.TempDir(), "graph.db")
+
+	if e := run([]string{ "import", "-db", unanalyzed, "-analyze=false", "../../testdata/nba.csv" }, nil, io.Discard, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if e := run([]string{ "explain", "-db", unanalyzed, `?x "former player" Celtics` }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); !strings.HasPrefix(got, "estimates are guesses") {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+
+	if e := run([]string{ "stats", "-db", db }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); !strings.HasPrefix(got, "12 edges, ") || !strings.Contains(got, "former player") {
+		t.Errorf("got %q", got)
+	}
+
+	stdout.Reset()
+
+	if e := run([]string{ "explain", "-db", db, "-analyze", `?x "former player" Celtics . ?x "former player" Timberwolves` }, nil, &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); !strings.Contains(got, "join") || !strings.Contains(got, "index scan") {
+		t.Errorf("got %q", got)
+	}
+
+	if e := run([]string{ "explain", "-db", db, "?x friend" }, nil, &stdout, io.Discard); e == nil {
+		t.Error("expected an incomplete pattern to fail")
+	}
+}
+
+func TestStderr(t *testing.T) {
+	db := importNBA(t)
+	var stdout, stderr bytes.Buffer
+
+	if e := run([]string{ "export", "-db", db, "-format", "jsonl", `"Paul Pierce" _ _` }, nil, &stdout, &stderr); e != nil {
+		t.Fatal(e)
+	}
+
+	if got := stdout.String(); got != `{"s":"Paul Pierce","p":"former player","o":"Celtics"}` + "\n" {
+		t.Errorf("got %q on stdout", got)
+	}
+
+	if got := stderr.String(); got != "exported 1 edges\n" {
+		t.Errorf("got %q on stderr", got)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+
+	if e := run([]string{ "nonsense" }, nil, &stdout, &stderr); e == nil {
+		t.Error("expected an unknown command to fail")
+	}
+
+	if stdout.Len() != 0 || !strings.Contains(stderr.String(), "usage: simplegraph <command>") {
+		t.Errorf("got %q on stdout and %q on stderr", stdout.String(), stderr.String())
+	}
+
+	stderr.Reset()
+
+	if e := run([]string{ "query", "-h" }, nil, &stdout, &stderr); e == nil || !strings.Contains(stderr.String(), "-format") {
+		t.Errorf("got %v and %q on stderr", e, stderr.String())
+	}
+}
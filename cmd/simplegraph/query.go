@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pH14/simplegraph"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "query",
+		summary: `print the bindings of triple patterns, e.g. '?x "played for" Celtics . ?x "played for" Timberwolves'`,
+		run:     runQuery,
+	})
+}
+
+func runQuery(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	format := flags.String("format", "table", "the output format: table, json or csv")
+	limit := flags.Int("limit", 0, "print at most this many results, if more than 0")
+
+	if e := flags.Parse(args); e != nil {
+		return e
+	}
+
+	patterns, e := parsePatterns(flags)
+
+	if e != nil {
+		return e
+	}
+
+	printer, e := newResultPrinter(*format, stdout, simplegraph.Variables(patterns...))
+
+	if e != nil {
+		return e
+	}
+
+	graph, closeStore, e := store.open()
+
+	if e != nil {
+		return e
+	}
+
+	defer closeStore()
+
+	_, e = query(context.Background(), graph, patterns, *limit, printer)
+	return e
+}
+
+// parsePatterns reads the triple patterns given as the command's arguments, which are joined with spaces
+func parsePatterns(flags *flag.FlagSet) ([]simplegraph.Query, error) {
+	if flags.NArg() == 0 {
+		return nil, fmt.Errorf("expected triple patterns, e.g. '?x \"played for\" Celtics'")
+	}
+
+	return simplegraph.ParsePatterns(strings.Join(flags.Args(), " "))
+}
+
+// query prints the results of the patterns, up to limit of them if it's more than 0, and returns how many it printed.
+// One pattern is searched, with the limit pushed down to its scan, and several are matched.
+func query(ctx context.Context, graph *simplegraph.SimpleGraph, patterns []simplegraph.Query, limit int,
+	printer resultPrinter) (int, error) {
+
+	var stream *simplegraph.SearchStream
+	var e error
+
+	if len(patterns) == 1 && limit > 0 {
+		stream, e = graph.Search(ctx, patterns[0].WithLimit(limit))
+	} else if len(patterns) == 1 {
+		stream, e = graph.Search(ctx, patterns[0])
+	} else {
+		stream, e = graph.Match(ctx, patterns...)
+	}
+
+	if e != nil {
+		return 0, e
+	}
+
+	defer stream.Close()
+
+	count, stopped := 0, false
+	for result := range stream.Results() {
+		if limit > 0 && count == limit {
+			stopped = true
+			break
+		}
+
+		if e := printer.print(result); e != nil {
+			return count, e
+		}
+
+		count++
+	}
+
+	// stopping early cancels the query, which isn't a failure
+	if e := stream.Err(); e != nil && !stopped {
+		return count, e
+	}
+
+	return count, printer.flush()
+}
+
+// resultPrinter writes search results in one of the query command's formats
+type resultPrinter interface {
+	print(result *simplegraph.SearchResults) error
+	flush() error
+}
+
+func newResultPrinter(format string, w io.Writer, variables []string) (resultPrinter, error) {
+	switch format {
+	case "table":
+		return newTablePrinter(w, variables), nil
+	case "json":
+		return &jsonPrinter{ encoder: simplegraph.NewJSONLinesEncoder(w) }, nil
+	case "csv":
+		return newCSVPrinter(w, variables), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// tablePrinter aligns the results in columns, one per variable. It holds every result until flush to size them.
+type tablePrinter struct {
+	w         *tabwriter.Writer
+	variables []string
+}
+
+func newTablePrinter(w io.Writer, variables []string) *tablePrinter {
+	tp := &tablePrinter{ w: tabwriter.NewWriter(w, 0, 8, 2, ' ', 0), variables: variables }
+	fmt.Fprintln(tp.w, strings.Join(variables, "\t"))
+
+	return tp
+}
+
+func (tp *tablePrinter) print(result *simplegraph.SearchResults) error {
+	_, e := fmt.Fprintln(tp.w, strings.Join(bindingTexts(result, tp.variables), "\t"))
+	return e
+}
+
+func (tp *tablePrinter) flush() error {
+	return tp.w.Flush()
+}
+
+// jsonPrinter writes each result as a line of JSON, with its bindings and the edges that matched
+type jsonPrinter struct {
+	encoder *simplegraph.JSONLinesEncoder
+}
+
+func (jp *jsonPrinter) print(result *simplegraph.SearchResults) error {
+	return jp.encoder.EncodeResult(result)
+}
+
+func (jp *jsonPrinter) flush() error {
+	return jp.encoder.Flush()
+}
+
+type csvPrinter struct {
+	w         *csv.Writer
+	variables []string
+}
+
+func newCSVPrinter(w io.Writer, variables []string) *csvPrinter {
+	cp := &csvPrinter{ w: csv.NewWriter(w), variables: variables }
+	cp.w.Write(variables)
+
+	return cp
+}
+
+func (cp *csvPrinter) print(result *simplegraph.SearchResults) error {
+	return cp.w.Write(bindingTexts(result, cp.variables))
+}
+
+func (cp *csvPrinter) flush() error {
+	cp.w.Flush()
+	return cp.w.Error()
+}
+
+func bindingTexts(result *simplegraph.SearchResults, variables []string) []string {
+	texts := make([]string, len(variables))
+
+	for i, variable := range variables {
+		if value, ok := result.Get(variable); ok {
+			texts[i] = valueText(value)
+		}
+	}
+
+	return texts
+}
+
+// valueText renders a value as plain text, without quotes
+func valueText(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
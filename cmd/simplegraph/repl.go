@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pH14/simplegraph"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "repl",
+		summary: "query interactively, with history and completion of predicates",
+		run:     runREPL,
+	})
+}
+
+const replHelp = `Enter triple patterns separated by full stops, e.g.
+
+	?x "played for" Celtics . ?x "played for" Timberwolves
+
+Terms are ?variables, _ for anything, "quoted strings" or bare words. Objects of other types are written int(13),
+float(1.5), bool(true), string("text") or time(2006-01-02T15:04:05Z). Tab completes predicates.
+
+	:explain              print each query's plan before running it, or stop
+	:timing               print how long each query took, or stop
+	:format table|json|csv
+	:limit <n>            print at most n results, or all of them for 0
+	:predicates [prefix]  list the predicates, or those starting with prefix
+	:help
+	:quit
+`
+
+// historySize is the number of lines of history kept. The history file is trimmed back to it once it's twice as long.
+const historySize = 1000
+
+func runREPL(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	format := flags.String("format", "table", "the output format: table, json or csv")
+	limit := flags.Int("limit", 100, "print at most this many results of each query, or all of them for 0")
+	historyFile := flags.String("history", defaultHistoryFile(), "keep the history in this file, or nowhere if it's empty")
+
+	if e := flags.Parse(args); e != nil {
+		return e
+	}
+
+	if _, e := newResultPrinter(*format, io.Discard, nil); e != nil {
+		return e
+	}
+
+	graph, closeStore, e := store.open()
+
+	if e != nil {
+		return e
+	}
+
+	defer closeStore()
+
+	r := &repl{ graph: graph, out: stdout, format: *format, limit: *limit, timing: true }
+	var reader lineReader = &plainReader{ scanner: bufio.NewScanner(stdin) }
+
+	if file, ok := stdin.(*os.File); ok {
+		if restore, e := makeRaw(file.Fd()); e == nil {
+			restore()
+
+			history, lines, _ := readHistory(*historyFile)
+			reader = &terminalReader{
+				fd:           file.Fd(),
+				editor:       newLineEditor(stdin, stdout, history, r.complete),
+				historyFile:  *historyFile,
+				historyLines: lines,
+			}
+
+			fmt.Fprintln(stdout, "type :help for help")
+		}
+	}
+
+	return r.run(reader)
+}
+
+// repl runs the queries it reads, and the commands that change how it runs them
+type repl struct {
+	graph *simplegraph.SimpleGraph
+	out   io.Writer
+
+	format  string
+	limit   int
+	explain bool
+	timing  bool
+}
+
+func (r *repl) run(reader lineReader) error {
+	for {
+		line, e := reader.readLine("simplegraph> ")
+
+		if e == errInterrupted {
+			continue
+		} else if e == io.EOF {
+			return nil
+		} else if e != nil {
+			return e
+		}
+
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			if quit := r.command(line); quit {
+				return nil
+			}
+
+			continue
+		}
+
+		if e := r.query(line); e != nil {
+			fmt.Fprintln(r.out, "error:", e)
+		}
+	}
+}
+
+// command runs a line starting with :, and reports whether it was :quit
+func (r *repl) command(line string) bool {
+	fields := strings.Fields(line)
+	argument := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+
+	switch fields[0] {
+	case ":quit", ":q", ":exit":
+		return true
+	case ":help":
+		fmt.Fprint(r.out, replHelp)
+	case ":explain":
+		r.explain = !r.explain
+		fmt.Fprintln(r.out, "explain", onOff(r.explain))
+	case ":timing":
+		r.timing = !r.timing
+		fmt.Fprintln(r.out, "timing", onOff(r.timing))
+	case ":format":
+		if _, e := newResultPrinter(argument, io.Discard, nil); e != nil {
+			fmt.Fprintln(r.out, "error:", e)
+		} else {
+			r.format = argument
+		}
+	case ":limit":
+		if limit, e := strconv.Atoi(argument); e != nil || limit < 0 {
+			fmt.Fprintf(r.out, "error: expected a limit of 0 or more, got %q\n", argument)
+		} else {
+			r.limit = limit
+		}
+	case ":predicates":
+		predicates, e := r.graph.Predicates(context.Background(), []byte(argument), 0)
+
+		if e != nil {
+			fmt.Fprintln(r.out, "error:", e)
+		}
+
+		for _, predicate := range predicates {
+			fmt.Fprintln(r.out, string(predicate))
+		}
+	default:
+		fmt.Fprintf(r.out, "error: unknown command %v, try :help\n", fields[0])
+	}
+
+	return false
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+func (r *repl) query(line string) error {
+	patterns, e := simplegraph.ParsePatterns(line)
+
+	if e != nil {
+		return e
+	}
+
+	if r.explain {
+		plan, e := r.graph.Explain(patterns...)
+
+		if e != nil {
+			return e
+		}
+
+		fmt.Fprint(r.out, plan)
+	}
+
+	printer, e := newResultPrinter(r.format, r.out, simplegraph.Variables(patterns...))
+
+	if e != nil {
+		return e
+	}
+
+	// ctrl-c stops the query rather than the REPL
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	count, e := query(ctx, r.graph, patterns, r.limit, printer)
+	elapsed := time.Since(start)
+
+	if e != nil {
+		if errors.Is(e, context.Canceled) {
+			return errors.New("query interrupted")
+		}
+
+		return e
+	}
+
+	summary := fmt.Sprintf("%v results", count)
+	if count == 1 {
+		summary = "1 result"
+	}
+
+	if r.limit > 0 && count == r.limit {
+		summary += fmt.Sprintf(", limited to %v", r.limit)
+	}
+
+	if r.timing {
+		summary += fmt.Sprintf(" in %v", elapsed.Round(time.Microsecond))
+	}
+
+	fmt.Fprintln(r.out, summary)
+	return nil
+}
+
+// completionCount is the most predicates offered at once
+const completionCount = 50
+
+// complete offers the predicates that could finish the term being typed, if it's a predicate
+func (r *repl) complete(before []rune) (int, []string) {
+	start, partial, ok := predicateTerm(before)
+
+	if !ok {
+		return 0, nil
+	}
+
+	quoted := strings.HasPrefix(partial, `"`)
+	prefix := partial
+
+	if quoted {
+		prefix = strings.TrimPrefix(partial, `"`)
+
+		if unquoted, e := strconv.Unquote(`"` + prefix + `"`); e == nil {
+			prefix = unquoted
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2 * time.Second)
+	defer cancel()
+
+	predicates, e := r.graph.Predicates(ctx, []byte(prefix), completionCount)
+
+	if e != nil {
+		return 0, nil
+	}
+
+	completions := make([]string, len(predicates))
+
+	for i, predicate := range predicates {
+		completions[i] = patternTerm(string(predicate), quoted) + " "
+	}
+
+	return start, completions
+}
+
+// predicateTerm finds the term being typed at the end of the text, and reports whether it's in a pattern's predicate
+// position, along with the index of the rune it starts at
+func predicateTerm(before []rune) (start int, partial string, ok bool) {
+	terms := 0
+
+	for i := 0; i < len(before); {
+		if unicode.IsSpace(before[i]) {
+			i++
+			continue
+		}
+
+		start = i
+		depth, quoted := 0, false
+
+		for ; i < len(before); i++ {
+			r := before[i]
+
+			if quoted {
+				if r == '\\' {
+					i++
+				} else if r == '"' {
+					quoted = false
+				}
+
+				continue
+			}
+
+			if r == '"' {
+				quoted = true
+			} else if r == '(' {
+				depth++
+			} else if r == ')' && depth > 0 {
+				depth--
+			} else if unicode.IsSpace(r) && depth == 0 {
+				break
+			}
+		}
+
+		if i >= len(before) {
+			// still being typed
+			return start, string(before[start:]), terms == 1 && !strings.HasPrefix(string(before[start:]), "?")
+		}
+
+		// a full stop ends a pattern, as a term of its own or on the end of its last term, like ParsePatterns reads it
+		token := string(before[start:i])
+		endsPattern := token == "." || strings.HasSuffix(token, ".") &&
+			(strings.HasPrefix(token, `"`) || !strings.Contains(token[:len(token) - 1], "."))
+
+		if endsPattern {
+			terms = 0
+		} else {
+			terms++
+		}
+	}
+
+	return len(before), "", terms == 1
+}
+
+// patternTerm writes a value as a term ParsePatterns reads back, quoting it if it has to be
+func patternTerm(value string, quote bool) string {
+	bare := value != "" && value != "_" && value != "." && !strings.HasSuffix(value, ".") &&
+		!strings.ContainsAny(value, `"()?`) && !strings.ContainsFunc(value, func(r rune) bool {
+			return unicode.IsSpace(r) || !unicode.IsPrint(r)
+		})
+
+	if bare && !quote {
+		return value
+	}
+
+	return strconv.Quote(value)
+}
+
+// terminalReader edits lines in a terminal, which is only in raw mode while a line is being read, so ctrl-c can
+// interrupt queries. It appends each line to the history file.
+type terminalReader struct {
+	fd     uintptr
+	editor *lineEditor
+
+	historyFile  string
+	historyLines int
+}
+
+func (tr *terminalReader) readLine(prompt string) (string, error) {
+	restore, e := makeRaw(tr.fd)
+
+	if e != nil {
+		return "", e
+	}
+
+	line, e := tr.editor.readLine(prompt)
+	restore()
+
+	if e == nil && strings.TrimSpace(line) != "" {
+		// losing the history isn't worth interrupting the session for
+		_ = tr.record(line)
+	}
+
+	return line, e
+}
+
+// record appends the line to the history file, and trims the file once it's grown to twice historySize lines
+func (tr *terminalReader) record(line string) error {
+	if tr.historyFile == "" {
+		return nil
+	}
+
+	if e := appendHistory(tr.historyFile, line); e != nil {
+		return e
+	}
+
+	if tr.historyLines++; tr.historyLines <= 2 * historySize {
+		return nil
+	}
+
+	history, _, e := readHistory(tr.historyFile)
+
+	if e != nil {
+		return e
+	}
+
+	if e := writeHistory(tr.historyFile, history); e != nil {
+		return e
+	}
+
+	tr.historyLines = len(history)
+	return nil
+}
+
+func defaultHistoryFile() string {
+	home, e := os.UserHomeDir()
+
+	if e != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".simplegraph_history")
+}
+
+// readHistory returns the last historySize lines of the history file, and how many lines it has in all
+func readHistory(path string) ([]string, int, error) {
+	if path == "" {
+		return nil, 0, nil
+	}
+
+	file, e := os.Open(path)
+
+	if e != nil {
+		return nil, 0, e
+	}
+
+	defer file.Close()
+
+	var history []string
+	lines := 0
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		lines++
+		history = append(history, scanner.Text())
+
+		if len(history) > 2 * historySize {
+			history = append(history[:0], history[len(history) - historySize:]...)
+		}
+	}
+
+	if len(history) > historySize {
+		history = history[len(history) - historySize:]
+	}
+
+	return history, lines, scanner.Err()
+}
+
+// writeHistory replaces the history file with the lines, through a temporary file so it's never left part written
+func writeHistory(path string, history []string) error {
+	temporary := path + ".tmp"
+	file, e := os.OpenFile(temporary, os.O_CREATE | os.O_TRUNC | os.O_WRONLY, 0600)
+
+	if e != nil {
+		return e
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, line := range history {
+		fmt.Fprintln(writer, line)
+	}
+
+	if e := writer.Flush(); e != nil {
+		file.Close()
+		return e
+	}
+
+	if e := file.Close(); e != nil {
+		return e
+	}
+
+	return os.Rename(temporary, path)
+}
+
+func appendHistory(path, line string) error {
+	file, e := os.OpenFile(path, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0600)
+
+	if e != nil {
+		return e
+	}
+
+	if _, e := fmt.Fprintln(file, line); e != nil {
+		file.Close()
+		return e
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pH14/simplegraph"
+)
+
+func TestREPL(t *testing.T) {
+	db := importNBA(t)
+	input := strings.Join([]string{
+		`?x "former player" Celtics . ?x "former player" Timberwolves`,
+		":timing",
+		":limit 1",
+		":format csv",
+		`?x "former player" ?team`,
+		":explain",
+		`"Doc Rivers" coach ?team`,
+		"?x friend",
+		":nonsense",
+		":predicates former",
+		":quit",
+		"?x _ _",
+	}, "\n")
+
+	var stdout bytes.Buffer
+
+	if e := run([]string{ "repl", "-db", db, "-history", "" }, strings.NewReader(input), &stdout, io.Discard); e != nil {
+		t.Fatal(e)
+	}
+
+	got := regexp.MustCompile(` in [0-9.]+[µnm]?s\n`).ReplaceAllString(stdout.String(), " in TIME\n")
+	plan := regexp.MustCompile(`(?s)explain on\n.*?team\n`).FindString(got)
+
+	if !strings.Contains(plan, "index scan") {
+		t.Errorf("expected a plan, got %q", plan)
+	}
+
+	got = strings.Replace(got, plan, "explain on\nteam\n", 1)
+	want := "x\nAl Jefferson\nKevin Garnett\n2 results in TIME\n" +
+		"timing off\n" +
+		"x,team\nAl Jefferson,Celtics\n1 result, limited to 1\n" +
+		"explain on\nteam\nClippers\n1 result, limited to 1\n" +
+		"error: pattern 1 has 2 terms, expected a subject, predicate and object: ?x friend\n" +
+		"error: unknown command :nonsense, try :help\n" +
+		"former coach\nformer player\n"
+
+	if got != want {
+		t.Errorf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	complete := func(before []rune) (int, []string) {
+		return predicateCompletions(before, []string{ "coach", "former coach", "former player" })
+	}
+
+	tests := []struct {
+		name    string
+		history []string
+		keys    string
+		want    string
+	}{
+		{ name: "typing", keys: "?x coach ?y\r", want: "?x coach ?y" },
+		{ name: "backspace", keys: "?x coacj\x7fh\r", want: "?x coach" },
+		{ name: "editing in the middle", keys: "?x ?y\x1b[D\x1b[Dcoach \r", want: "?x coach ?y" },
+		{ name: "home, end and delete", keys: "x coach\x01?\x05 ?y\x01\x1b[3~\r", want: "x coach ?y" },
+		{ name: "ctrl-u and ctrl-w", keys: "junk\x15?x coach junk\x17?y\r", want: "?x coach ?y" },
+		{ name: "history", history: []string{ "first", "second" }, keys: "draft\x1b[A\x1b[A\x1b[B\x1b[B\r", want: "draft" },
+		{ name: "history recall", history: []string{ "first", "second" }, keys: "\x1b[A\x1b[A\r", want: "first" },
+		{ name: "complete the only match", keys: "?x c\t?y\r", want: "?x coach ?y" },
+		{ name: "complete what matches share", keys: "?x f\tp\t?y\r", want: `?x "former player" ?y` },
+		{ name: "complete quoted", keys: "?x \"former c\t?y\r", want: `?x "former coach" ?y` },
+		{ name: "no completion of subjects", keys: "c\t\r", want: "c" },
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			editor := newLineEditor(strings.NewReader(tt.keys), &out, tt.history, complete)
+
+			got, e := editor.readLine("> ")
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if got != tt.want {
+				t.Errorf("readLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineEditor_Lists(t *testing.T) {
+	var out bytes.Buffer
+	complete := func(before []rune) (int, []string) {
+		return predicateCompletions(before, []string{ "coach", "former coach", "former player" })
+	}
+
+	editor := newLineEditor(strings.NewReader("?x \"former \t\t\x03\x04"), &out, nil, complete)
+
+	if _, e := editor.readLine("> "); e != errInterrupted {
+		t.Errorf("expected ctrl-c to interrupt, got %v", e)
+	}
+
+	if !strings.Contains(out.String(), "\r\n\"former coach\"   \"former player\" \r\n") {
+		t.Errorf("expected the completions to be listed, got %q", out.String())
+	}
+
+	if _, e := editor.readLine("> "); e == nil || e.Error() != "EOF" {
+		t.Errorf("expected ctrl-d to end the input, got %v", e)
+	}
+}
+
+func TestPredicateTerm(t *testing.T) {
+	tests := []struct {
+		before    string
+		wantStart int
+		want      string
+		wantOK    bool
+	}{
+		{ before: "", wantStart: 0, want: "", wantOK: false },
+		{ before: "?x", wantStart: 0, want: "?x", wantOK: false },
+		{ before: "?x ", wantStart: 3, want: "", wantOK: true },
+		{ before: "?x pl", wantStart: 3, want: "pl", wantOK: true },
+		{ before: `?x "played f`, wantStart: 3, want: `"played f`, wantOK: true },
+		{ before: "?x ?p", wantStart: 3, want: "?p", wantOK: false },
+		{ before: `?x "played for" `, wantStart: 16, want: "", wantOK: false },
+		{ before: `?x "played for" Celtics . ?y pl`, wantStart: 29, want: "pl", wantOK: true },
+		{ before: `?x "played for" Celtics. ?y `, wantStart: 28, want: "", wantOK: true },
+		{ before: `?x note string("a b") . ?y `, wantStart: 27, want: "", wantOK: true },
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.before, func(t *testing.T) {
+			start, got, ok := predicateTerm([]rune(tt.before))
+
+			if start != tt.wantStart || got != tt.want || ok != tt.wantOK {
+				t.Errorf("predicateTerm() = %v, %q, %v, want %v, %q, %v", start, got, ok, tt.wantStart, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPatternTerm(t *testing.T) {
+	for _, value := range []string{ "coach", "former player", "_", "?x", "St. Louis.", "f(x)", `say "hi"`, "tab\there", "" } {
+		patterns, e := simplegraph.ParsePatterns("?s " + patternTerm(value, false) + " ?o")
+
+		if e != nil {
+			t.Errorf("%q: %v", value, e)
+			continue
+		}
+
+		want := simplegraph.NewQuery().WithSubjectVariable("s").WithPredicate([]byte(value)).WithObjectVariable("o")
+
+		if !reflect.DeepEqual(patterns, []simplegraph.Query{ want }) {
+			t.Errorf("%q read back as %v", value, patterns)
+		}
+	}
+}
+
+// predicateCompletions completes predicates from a fixed list, like repl.complete does from the graph
+func predicateCompletions(before []rune, predicates []string) (int, []string) {
+	start, partial, ok := predicateTerm(before)
+
+	if !ok {
+		return 0, nil
+	}
+
+	quoted := strings.HasPrefix(partial, `"`)
+	var completions []string
+
+	for _, predicate := range predicates {
+		if strings.HasPrefix(predicate, strings.TrimPrefix(partial, `"`)) {
+			completions = append(completions, patternTerm(predicate, quoted) + " ")
+		}
+	}
+
+	return start, completions
+}
+
+func TestTerminalReader_TrimsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	reader := &terminalReader{ historyFile: path }
+
+	for i := 0; i < 2 * historySize; i++ {
+		if e := reader.record(fmt.Sprintf("line %v", i)); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if history, lines, _ := readHistory(path); lines != 2 * historySize || len(history) != historySize {
+		t.Fatalf("got %v lines, %v of them kept, before the file reached its limit", lines, len(history))
+	}
+
+	// one more line trims the file to the last historySize
+	if e := reader.record("last"); e != nil {
+		t.Fatal(e)
+	}
+
+	history, lines, e := readHistory(path)
+
+	if e != nil || lines != historySize || history[0] != fmt.Sprintf("line %v", historySize + 1) || history[len(history) - 1] != "last" {
+		t.Errorf("got %v lines from %v to %v: %v", lines, history[0], history[len(history) - 1], e)
+	}
+
+	if reader.historyLines != historySize {
+		t.Errorf("expected the reader to count %v lines, got %v", historySize, reader.historyLines)
+	}
+}
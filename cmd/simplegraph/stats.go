@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+func init() {
+	commands = append(commands, &command{
+		name:    "stats",
+		summary: "count the graph's edges, subjects and objects, in all and by predicate, refreshing the query planner's statistics",
+		run:     runStats,
+	})
+}
+
+func runStats(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var store storeFlags
+	store.register(flags)
+
+	asJSON := flags.Bool("json", false, "print the statistics as JSON, with each predicate's object histogram")
+
+	if e := flags.Parse(args); e != nil {
+		return e
+	}
+
+	graph, closeStore, e := store.open()
+
+	if e != nil {
+		return e
+	}
+
+	defer closeStore()
+
+	stats, e := graph.Analyze(context.Background())
+
+	if e != nil {
+		return e
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(stats)
+	}
+
+	fmt.Fprintf(stdout, "%v edges, %v subjects, %v objects, %v predicates\n\n",
+		stats.Edges, stats.DistinctSubjects, stats.DistinctObjects, len(stats.Predicates))
+
+	var predicates []string
+	for predicate := range stats.Predicates {
+		predicates = append(predicates, predicate)
+	}
+
+	sort.Strings(predicates)
+
+	w := tabwriter.NewWriter(stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "predicate\tedges\tsubjects\tobjects\t")
+
+	for _, predicate := range predicates {
+		ps := stats.Predicates[predicate]
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t\n", predicate, ps.Edges, ps.DistinctSubjects, ps.DistinctObjects)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// makeRaw isn't supported here, so the REPL reads plain lines, without history or completion
+func makeRaw(fd uintptr) (func() error, error) {
+	return nil, errors.New("raw terminals aren't supported on this platform")
+}
@@ -0,0 +1,38 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal into raw mode, so keys arrive as they're pressed and aren't echoed, and returns a
+// function that restores it. It fails if fd isn't a terminal.
+func makeRaw(fd uintptr) (func() error, error) {
+	var saved syscall.Termios
+
+	if e := termios(fd, getTermios, &saved); e != nil {
+		return nil, e
+	}
+
+	raw := saved
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if e := termios(fd, setTermios, &raw); e != nil {
+		return nil, e
+	}
+
+	return func() error { return termios(fd, setTermios, &saved) }, nil
+}
+
+func termios(fd uintptr, request uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
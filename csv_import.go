@@ -0,0 +1,366 @@
+package simplegraph
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const defaultImportBatchSize = 1000
+
+// maxCSVLine is the longest line, or quoted record spanning lines, ImportCSV reads
+const maxCSVLine = 1 << 20
+
+// CSVOptions describe the layout of a file of delimited triples. The zero value reads comma separated
+// subject, predicate, object rows, with double quoted fields and the space around fields trimmed.
+type CSVOptions struct {
+	// Delimiter separates fields, ',' by default. Use '\t' for TSV.
+	Delimiter rune
+
+	// Quote encloses fields that contain the delimiter, '"' by default. A quote inside a quoted field is written
+	// twice. Quoted fields can span lines, up to 1MB in all.
+	Quote rune
+
+	// NoQuoting reads quote characters like any other
+	NoQuoting bool
+
+	// KeepSpace stops the space around unquoted fields being trimmed
+	KeepSpace bool
+
+	// Header skips the first line, and lets Columns refer to fields by its names
+	Header bool
+
+	// Columns picks the subject, predicate and object fields, in that order, by header name or zero based position.
+	// The default is the first three fields.
+	Columns []string
+
+	// BatchSize is the number of edges written in each call to AddEdges, 1000 by default
+	BatchSize int
+
+	// DryRun reads the whole file and reports every malformed line, without writing anything
+	DryRun bool
+}
+
+// ImportReport describes what an import read, and in a dry run, everything wrong with it
+type ImportReport struct {
+	Lines int
+	Edges int
+
+	// Malformed holds the lines a dry run couldn't read. A real import stops at the first.
+	Malformed []*LineError
+}
+
+// LineError is a line of an imported file that couldn't be read, or the first line of a record spanning several
+type LineError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (le *LineError) Error() string {
+	return fmt.Sprintf("line %v: %v", le.Line, le.Err)
+}
+
+func (le *LineError) Unwrap() error {
+	return le.Err
+}
+
+// ImportCSV streams delimited subject, predicate, object rows into the graph as edges with []byte fields, in batches.
+// Each batch is written atomically, but the import as a whole isn't: if it stops at a malformed line or a failed
+// write, the batches already written stay written, and the rest of the batch it was reading isn't.
+func (graph *SimpleGraph) ImportCSV(ctx context.Context, r io.Reader, options CSVOptions) (*ImportReport, error) {
+	if options.Delimiter == 0 {
+		options.Delimiter = ','
+	}
+
+	if options.NoQuoting {
+		options.Quote = 0
+	} else if options.Quote == 0 {
+		options.Quote = '"'
+	}
+
+	if options.Delimiter == options.Quote || options.Delimiter == '\n' || options.Delimiter == '\r' {
+		return nil, fmt.Errorf("%q can't be used as the delimiter", options.Delimiter)
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportBatchSize
+	}
+
+	report := &ImportReport{}
+	batch := make([]Edge, 0, options.BatchSize)
+
+	flush := func() error {
+		if !options.DryRun && len(batch) > 0 {
+			if e := graph.AddEdges(batch); e != nil {
+				return e
+			}
+		}
+
+		report.Edges += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	// without a header, the columns can only be positions
+	columns, e := headerColumns(nil, options.Columns)
+
+	if e != nil && !options.Header {
+		return nil, e
+	}
+
+	needHeader := options.Header
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxCSVLine)
+
+	// a scan error is put down to the line it stopped at
+	scanError := func() error {
+		if e := scanner.Err(); e != nil {
+			return &LineError{ Line: report.Lines + 1, Err: e }
+		}
+
+		return nil
+	}
+
+	for scanner.Scan() {
+		if e := ctx.Err(); e != nil {
+			return report, e
+		}
+
+		report.Lines++
+		line := report.Lines
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// a quoted field carries on over the next line
+		if quoted := endsQuoted(text, false, options); quoted {
+			var record strings.Builder
+			record.WriteString(text)
+
+			for quoted && record.Len() < maxCSVLine && scanner.Scan() {
+				report.Lines++
+				next := strings.TrimSuffix(scanner.Text(), "\r")
+				record.WriteString("\n")
+				record.WriteString(next)
+				quoted = endsQuoted(next, true, options)
+			}
+
+			text = record.String()
+		}
+
+		if e := scanError(); e != nil {
+			return report, e
+		}
+
+		fields, e := splitDelimited(text, options)
+
+		if needHeader {
+			needHeader = false
+
+			if e == nil {
+				columns, e = headerColumns(fields, options.Columns)
+			}
+
+			if e != nil {
+				return report, &LineError{ Line: line, Text: text, Err: e }
+			}
+
+			continue
+		}
+
+		var edge Edge
+		if e == nil {
+			edge, e = edgeFromFields(fields, columns)
+		}
+
+		if e != nil {
+			lineError := &LineError{ Line: line, Text: text, Err: e }
+
+			if !options.DryRun {
+				return report, lineError
+			}
+
+			report.Malformed = append(report.Malformed, lineError)
+			continue
+		}
+
+		batch = append(batch, edge)
+
+		if len(batch) == options.BatchSize {
+			if e := flush(); e != nil {
+				return report, e
+			}
+		}
+	}
+
+	if e := scanError(); e != nil {
+		return report, e
+	}
+
+	return report, flush()
+}
+
+// headerColumns resolves the column mapping against the header's field names, falling back on positions
+func headerColumns(header []string, mapping []string) ([]int, error) {
+	if len(mapping) == 0 {
+		return []int{ 0, 1, 2 }, nil
+	}
+
+	if len(mapping) != 3 {
+		return nil, fmt.Errorf("the columns must name the subject, predicate and object, not %v", mapping)
+	}
+
+	columns := make([]int, 3)
+
+	MAPPING:
+	for i, name := range mapping {
+		for j, field := range header {
+			if field == name {
+				columns[i] = j
+				continue MAPPING
+			}
+		}
+
+		position, e := strconv.Atoi(name)
+
+		if e != nil || position < 0 {
+			return nil, fmt.Errorf("no column %q", name)
+		}
+
+		columns[i] = position
+	}
+
+	return columns, nil
+}
+
+func edgeFromFields(fields []string, columns []int) (Edge, error) {
+	for _, column := range columns {
+		if column >= len(fields) {
+			return Edge{}, fmt.Errorf("expected at least %v fields, found %v", column + 1, len(fields))
+		}
+	}
+
+	subject, predicate, object := fields[columns[0]], fields[columns[1]], fields[columns[2]]
+
+	if subject == "" || predicate == "" {
+		return Edge{}, errors.New("the subject and predicate can't be empty")
+	}
+
+	return NewEdge([]byte(subject), []byte(predicate), []byte(object)), nil
+}
+
+// endsQuoted reports whether a line, begun inside a quoted field or not, ends inside one. It follows the quotes the
+// way splitDelimited does, so a record's lines are each only read once before it's split.
+func endsQuoted(line string, quoted bool, options CSVOptions) bool {
+	if options.Quote == 0 {
+		return false
+	}
+
+	fieldStart := !quoted
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case quoted:
+			if r != options.Quote {
+				continue
+			}
+
+			if i + 1 < len(runes) && runes[i + 1] == options.Quote {
+				i++
+				continue
+			}
+
+			quoted = false
+		case r == options.Delimiter:
+			fieldStart = true
+		case fieldStart && r == options.Quote:
+			quoted = true
+			fieldStart = false
+		case fieldStart && !options.KeepSpace && unicode.IsSpace(r):
+		default:
+			fieldStart = false
+		}
+	}
+
+	return quoted
+}
+
+// splitDelimited splits a line into its fields, unquoting and trimming them as the options say
+func splitDelimited(line string, options CSVOptions) ([]string, error) {
+	var fields []string
+	runes := []rune(line)
+	i := 0
+
+	for {
+		start := i
+		if !options.KeepSpace {
+			for i < len(runes) && runes[i] != options.Delimiter && unicode.IsSpace(runes[i]) {
+				i++
+			}
+		}
+
+		if options.Quote != 0 && i < len(runes) && runes[i] == options.Quote {
+			var field strings.Builder
+			i++
+
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated quote in field %v", len(fields) + 1)
+				}
+
+				if runes[i] == options.Quote {
+					if i + 1 < len(runes) && runes[i + 1] == options.Quote {
+						field.WriteRune(options.Quote)
+						i += 2
+						continue
+					}
+
+					i++
+					break
+				}
+
+				field.WriteRune(runes[i])
+				i++
+			}
+
+			for i < len(runes) && runes[i] != options.Delimiter && unicode.IsSpace(runes[i]) && !options.KeepSpace {
+				i++
+			}
+
+			if i < len(runes) && runes[i] != options.Delimiter {
+				return nil, fmt.Errorf("unexpected %q after the closing quote of field %v", runes[i], len(fields) + 1)
+			}
+
+			fields = append(fields, field.String())
+		} else {
+			for i < len(runes) && runes[i] != options.Delimiter {
+				i++
+			}
+
+			field := string(runes[start:i])
+			if !options.KeepSpace {
+				field = strings.TrimSpace(field)
+			}
+
+			fields = append(fields, field)
+		}
+
+		if i >= len(runes) {
+			return fields, nil
+		}
+
+		// skip the delimiter
+		i++
+	}
+}
@@ -0,0 +1,270 @@
+package simplegraph
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func queriedEdges(t *testing.T, graph *SimpleGraph, query Query) []Edge {
+	stream, e := graph.GetEdges(context.Background(), query)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var edges []Edge
+	for edge := range stream.Edges() {
+		edges = append(edges, *edge)
+	}
+
+	if e := stream.Err(); e != nil {
+		t.Fatal(e)
+	}
+
+	return edges
+}
+
+func importedEdges(t *testing.T, graph *SimpleGraph) []string {
+	var edges []string
+
+	for _, edge := range queriedEdges(t, graph, NewQuery()) {
+		edges = append(edges, fmt.Sprintf("(%q, %q, %v)", edge.Subject(), edge.Predicate(), formatValue(edge.Object())))
+	}
+
+	return edges
+}
+
+func TestSimpleGraph_ImportCSVFile(t *testing.T) {
+	file, e := os.Open("testdata/nba.csv")
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer file.Close()
+
+	graph := NewSimpleGraph(NewMemoryGraph())
+	report, e := graph.ImportCSV(context.Background(), file, CSVOptions{ BatchSize: 5 })
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if report.Lines != 12 || report.Edges != 12 {
+		t.Errorf("got %+v, want 12 lines and edges", report)
+	}
+
+	results, e := graph.Match(context.Background(),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("former player")).WithObject([]byte("Celtics")),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("former player")).WithObject([]byte("Timberwolves")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if got, want := collectBindings(t, results), []string{"x=Al Jefferson ", "x=Kevin Garnett "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSimpleGraph_ImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options CSVOptions
+		want    []string
+	}{
+		{
+			"it trims space",
+			" a ,  b,c \n",
+			CSVOptions{},
+			[]string{`("a", "b", "c")`},
+		},
+		{
+			"it keeps space",
+			" a , b,c\n",
+			CSVOptions{ KeepSpace: true },
+			[]string{`(" a ", " b", "c")`},
+		},
+		{
+			"it reads TSV, skipping blank lines",
+			"a b\tc\td\r\n\ne\tf\tg h\n",
+			CSVOptions{ Delimiter: '\t' },
+			[]string{`("a b", "c", "d")`, `("e", "f", "g h")`},
+		},
+		{
+			"it unquotes fields",
+			`"a, b", "say ""hi""" ,c` + "\n",
+			CSVOptions{},
+			[]string{`("a, b", "say \"hi\"", "c")`},
+		},
+		{
+			"it uses other quotes",
+			`'a, b',c,"d"` + "\n",
+			CSVOptions{ Quote: '\'' },
+			[]string{`("a, b", "c", "\"d\"")`},
+		},
+		{
+			"it reads quotes literally without quoting",
+			`"a,b",c` + "\n",
+			CSVOptions{ NoQuoting: true },
+			[]string{`("\"a", "b\"", "c")`},
+		},
+		{
+			"it skips the header and maps columns by name",
+			"team,player,role,notes\nCeltics,Paul Pierce,former player,captain\n",
+			CSVOptions{ Header: true, Columns: []string{ "player", "role", "team" } },
+			[]string{`("Paul Pierce", "former player", "Celtics")`},
+		},
+		{
+			"it maps columns by position",
+			"Celtics,Paul Pierce,former player\n",
+			CSVOptions{ Columns: []string{ "1", "2", "0" } },
+			[]string{`("Paul Pierce", "former player", "Celtics")`},
+		},
+		{
+			"it reads quoted fields over several lines",
+			"a,b,\"c\r\n\nd\"\r\ne,f,g\n",
+			CSVOptions{},
+			[]string{`("a", "b", "c\n\nd")`, `("e", "f", "g")`},
+		},
+		{
+			"it reads a quoted field of many lines",
+			"a,b,\"" + strings.Repeat("c\n", 3) + "\"\n",
+			CSVOptions{},
+			[]string{`("a", "b", "c\nc\nc\n")`},
+		},
+		{
+			"it allows empty objects",
+			"a,b,\n",
+			CSVOptions{},
+			[]string{`("a", "b", "")`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := NewSimpleGraph(NewMemoryGraph())
+
+			if _, e := graph.ImportCSV(context.Background(), strings.NewReader(tt.input), tt.options); e != nil {
+				t.Fatal(e)
+			}
+
+			if got := importedEdges(t, graph); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleGraph_ImportCSVMalformed(t *testing.T) {
+	input := strings.Join([]string{
+		"a,b,c",
+		"a,b",
+		"",
+		`"a"x,b,c`,
+		",b,c",
+		"d,e,f",
+		// an unterminated quote runs on to the end
+		`"a,b,c`,
+	}, "\n")
+
+	graph := NewSimpleGraph(NewMemoryGraph())
+	report, e := graph.ImportCSV(context.Background(), strings.NewReader(input), CSVOptions{ DryRun: true })
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var lines []int
+	for _, malformed := range report.Malformed {
+		lines = append(lines, malformed.Line)
+	}
+
+	if want := []int{ 2, 4, 5, 7 }; !reflect.DeepEqual(lines, want) {
+		t.Errorf("got malformed lines %v, want %v", lines, want)
+	}
+
+	if report.Lines != 7 || report.Edges != 2 {
+		t.Errorf("got %+v, want 7 lines and 2 edges", report)
+	}
+
+	if got := importedEdges(t, graph); len(got) != 0 {
+		t.Errorf("a dry run wrote %v", got)
+	}
+
+	// a real import stops at the first, before writing the batch it's in
+	_, e = graph.ImportCSV(context.Background(), strings.NewReader(input), CSVOptions{})
+
+	var lineError *LineError
+	if !errors.As(e, &lineError) || lineError.Line != 2 || lineError.Text != "a,b" {
+		t.Errorf("got %v, want an error at line 2", e)
+	}
+
+	if got := importedEdges(t, graph); len(got) != 0 {
+		t.Errorf("got %v, want nothing written", got)
+	}
+
+	_, e = graph.ImportCSV(context.Background(), strings.NewReader("s,p,o\n"), CSVOptions{ Header: true, Columns: []string{ "s", "p", "x" } })
+	if e == nil || !strings.Contains(e.Error(), `no column "x"`) {
+		t.Errorf("got %v, want an unknown column", e)
+	}
+}
+
+func Test_endsQuoted(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		quoted  bool
+		options CSVOptions
+		want    bool
+	}{
+		{"it opens a quoted field", `a,"b`, false, CSVOptions{ Delimiter: ',', Quote: '"' }, true},
+		{"it closes a quoted field", `b",c`, true, CSVOptions{ Delimiter: ',', Quote: '"' }, false},
+		{"it skips doubled quotes", `a,"b""`, false, CSVOptions{ Delimiter: ',', Quote: '"' }, true},
+		{"it skips space before a quote", `a,  "b`, false, CSVOptions{ Delimiter: ',', Quote: '"' }, true},
+		{"it keeps space before a quote", `a, "b`, false, CSVOptions{ Delimiter: ',', Quote: '"', KeepSpace: true }, false},
+		{"it reads quotes within a field literally", `a,b"c`, false, CSVOptions{ Delimiter: ',', Quote: '"' }, false},
+		{"it reads quotes literally without quoting", `a,"b`, false, CSVOptions{ Delimiter: ',' }, false},
+		{"it follows several fields", `"a","b`, false, CSVOptions{ Delimiter: ',', Quote: '"' }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := endsQuoted(tt.line, tt.quoted, tt.options); got != tt.want {
+				t.Errorf("endsQuoted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleGraph_ImportCSVLineNumbers(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+
+	// errors are put down to the first line of a record
+	input := "a,b,\"c\nd\"\ne,\"f\n\",g,\"h\"x\ni,j,k\n"
+	report, e := graph.ImportCSV(context.Background(), strings.NewReader(input), CSVOptions{ DryRun: true })
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if len(report.Malformed) != 1 || report.Malformed[0].Line != 3 || report.Lines != 5 || report.Edges != 2 {
+		t.Errorf("got %+v, want line 3 of 5 malformed", report)
+	}
+
+	// as are lines too long to read
+	input = "a,b,c\nd,e," + strings.Repeat("f", maxCSVLine) + "\n"
+	_, e = graph.ImportCSV(context.Background(), strings.NewReader(input), CSVOptions{})
+
+	var lineError *LineError
+	if !errors.As(e, &lineError) || lineError.Line != 2 || !errors.Is(e, bufio.ErrTooLong) {
+		t.Errorf("got %v, want line 2 too long", e)
+	}
+}
@@ -0,0 +1,310 @@
+package simplegraph
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// Style holds Graphviz attributes, like color, shape or style. GraphML writes them as data of the same names.
+type Style map[string]string
+
+// DiagramOptions control ExportDOT and ExportGraphML
+type DiagramOptions struct {
+	// MaxEdges stops the diagram after that many edges, so a busy neighbourhood stays legible. Zero draws every edge.
+	MaxEdges int
+
+	// EdgeStyles styles the edges of each predicate
+	EdgeStyles map[string]Style
+
+	// NodeStyles styles the objects of the edges of each predicate, so e.g. teams can be told from players. A node
+	// that's the object of several styled predicates gets the attributes of each, those seen first winning a clash.
+	NodeStyles map[string]Style
+}
+
+// diagram is the nodes and edges to draw. Nodes are numbered in the order they're first seen.
+type diagram struct {
+	nodes     []diagramNode
+	edges     []diagramEdge
+	truncated bool
+}
+
+type diagramNode struct {
+	label string
+	style Style
+}
+
+type diagramEdge struct {
+	source, target int
+	label          string
+	style          Style
+}
+
+// ExportDOT draws the edges matching the patterns as a Graphviz digraph, with subjects and objects as nodes and
+// predicates as edge labels. One pattern is read with GetEdges; several are matched with Match, and every edge of
+// every result is drawn. It returns the number of edges drawn.
+func (graph *SimpleGraph) ExportDOT(ctx context.Context, w io.Writer, options DiagramOptions, patterns ...Query) (int, error) {
+	d, e := graph.diagram(ctx, options, patterns)
+
+	if e != nil {
+		return 0, e
+	}
+
+	out := bufio.NewWriter(w)
+	_, _ = out.WriteString("digraph simplegraph {\n")
+
+	if d.truncated {
+		_, _ = fmt.Fprintf(out, "\t// stopped after %v edges\n", len(d.edges))
+	}
+
+	for i, node := range d.nodes {
+		_, _ = fmt.Fprintf(out, "\tn%v [%v];\n", i, dotAttributes(node.label, node.style))
+	}
+
+	for _, edge := range d.edges {
+		_, _ = fmt.Fprintf(out, "\tn%v -> n%v [%v];\n", edge.source, edge.target, dotAttributes(edge.label, edge.style))
+	}
+
+	_, _ = out.WriteString("}\n")
+	return len(d.edges), out.Flush()
+}
+
+func dotAttributes(label string, style Style) string {
+	attributes := []string{ "label=" + dotQuote(label) }
+
+	for _, name := range styleNames(style) {
+		attributes = append(attributes, name + "=" + dotQuote(style[name]))
+	}
+
+	return strings.Join(attributes, ", ")
+}
+
+func dotQuote(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(text) + `"`
+}
+
+func styleNames(style Style) []string {
+	names := make([]string, 0, len(style))
+
+	for name := range style {
+		if name != "label" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ExportGraphML draws the same diagram as ExportDOT as a GraphML document. Labels and styles are written as data,
+// with a key declared for each.
+func (graph *SimpleGraph) ExportGraphML(ctx context.Context, w io.Writer, options DiagramOptions, patterns ...Query) (int, error) {
+	d, e := graph.diagram(ctx, options, patterns)
+
+	if e != nil {
+		return 0, e
+	}
+
+	// every style attribute used needs a key
+	nodeKeys, edgeKeys := Style{}, Style{}
+	for _, node := range d.nodes {
+		for name := range node.style {
+			nodeKeys[name] = ""
+		}
+	}
+
+	for _, edge := range d.edges {
+		for name := range edge.style {
+			edgeKeys[name] = ""
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	_, _ = out.WriteString(xml.Header)
+	_, _ = out.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	_, _ = out.WriteString(`  <key id="label" for="all" attr.name="label" attr.type="string"/>` + "\n")
+
+	for _, keys := range []struct {
+		kind  string
+		names Style
+	}{{ "node", nodeKeys }, { "edge", edgeKeys }} {
+		for _, name := range styleNames(keys.names) {
+			_, _ = fmt.Fprintf(out, `  <key id="%v.%v" for="%v" attr.name="%v" attr.type="string"/>`+"\n",
+				keys.kind, xmlEscape(name), keys.kind, xmlEscape(name))
+		}
+	}
+
+	_, _ = out.WriteString(`  <graph id="simplegraph" edgedefault="directed">` + "\n")
+
+	if d.truncated {
+		_, _ = fmt.Fprintf(out, "    <!-- stopped after %v edges -->\n", len(d.edges))
+	}
+
+	for i, node := range d.nodes {
+		_, _ = fmt.Fprintf(out, `    <node id="n%v">`, i)
+		writeGraphMLData(out, "node", node.label, node.style)
+		_, _ = out.WriteString("</node>\n")
+	}
+
+	for i, edge := range d.edges {
+		_, _ = fmt.Fprintf(out, `    <edge id="e%v" source="n%v" target="n%v">`, i, edge.source, edge.target)
+		writeGraphMLData(out, "edge", edge.label, edge.style)
+		_, _ = out.WriteString("</edge>\n")
+	}
+
+	_, _ = out.WriteString("  </graph>\n</graphml>\n")
+	return len(d.edges), out.Flush()
+}
+
+func writeGraphMLData(out *bufio.Writer, kind, label string, style Style) {
+	_, _ = fmt.Fprintf(out, `<data key="label">%v</data>`, xmlEscape(label))
+
+	for _, name := range styleNames(style) {
+		_, _ = fmt.Fprintf(out, `<data key="%v.%v">%v</data>`, kind, xmlEscape(name), xmlEscape(style[name]))
+	}
+}
+
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
+
+// mergeStyles returns a new style with the attributes of both, keeping style's where they both set one
+func mergeStyles(style, more Style) Style {
+	merged := Style{}
+
+	for name, value := range more {
+		merged[name] = value
+	}
+
+	for name, value := range style {
+		merged[name] = value
+	}
+
+	return merged
+}
+
+// diagram gathers the edges to draw, up to options.MaxEdges
+func (graph *SimpleGraph) diagram(ctx context.Context, options DiagramOptions, patterns []Query) (*diagram, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("a diagram needs at least one pattern")
+	}
+
+	d := &diagram{}
+	nodes := make(map[string]int)
+	drawn := make(map[string]bool)
+
+	// a node seen again as the object of a styled predicate takes on that style too, whatever it was first seen as
+	node := func(value interface{}, style Style) int {
+		key := string(tuple.Tuple{ encodeValue(value) }.Pack())
+
+		if i, ok := nodes[key]; ok {
+			if len(style) > 0 {
+				d.nodes[i].style = mergeStyles(d.nodes[i].style, style)
+			}
+
+			return i
+		}
+
+		nodes[key] = len(d.nodes)
+		d.nodes = append(d.nodes, diagramNode{ label: diagramLabel(value), style: style })
+		return nodes[key]
+	}
+
+	// add reports whether there's room for more edges
+	add := func(edge *Edge) bool {
+		key := string(Indices["spo"].toBytes(edge))
+
+		if drawn[key] {
+			return true
+		}
+
+		if options.MaxEdges > 0 && len(d.edges) == options.MaxEdges {
+			d.truncated = true
+			return false
+		}
+
+		drawn[key] = true
+		predicate := string(edge.predicate)
+		source := node(edge.subject, nil)
+		target := node(edge.object, options.NodeStyles[predicate])
+
+		d.edges = append(d.edges, diagramEdge{
+			source: source,
+			target: target,
+			label:  diagramLabel(edge.predicate),
+			style:  options.EdgeStyles[predicate],
+		})
+
+		return true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if len(patterns) == 1 {
+		query := patterns[0]
+
+		// one more than the limit shows whether anything was left out
+		if options.MaxEdges > 0 && query.limit == 0 {
+			query = query.WithLimit(options.MaxEdges + 1)
+		}
+
+		stream, e := graph.GetEdges(ctx, query)
+
+		if e != nil {
+			return nil, e
+		}
+
+		for edge := range stream.Edges() {
+			if !add(edge) {
+				return d, nil
+			}
+		}
+
+		return d, stream.Err()
+	}
+
+	stream, e := graph.Match(ctx, patterns...)
+
+	if e != nil {
+		return nil, e
+	}
+
+	for result := range stream.Results() {
+		for _, edge := range result.edges {
+			if !add(edge) {
+				return d, nil
+			}
+		}
+	}
+
+	return d, stream.Err()
+}
+
+// diagramLabel renders a node or edge label, quoting bytes that aren't UTF-8
+func diagramLabel(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+
+		return strconv.Quote(string(v))
+	case string:
+		return v
+	default:
+		return formatValue(v)
+	}
+}
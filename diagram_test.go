@@ -0,0 +1,152 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func nbaGraph(t *testing.T) *SimpleGraph {
+	file, e := os.Open("testdata/nba.csv")
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	defer file.Close()
+
+	graph := NewSimpleGraph(NewMemoryGraph())
+	if _, e := graph.ImportCSV(context.Background(), file, CSVOptions{}); e != nil {
+		t.Fatal(e)
+	}
+
+	return graph
+}
+
+func TestSimpleGraph_ExportDOT(t *testing.T) {
+	graph := nbaGraph(t)
+	var document bytes.Buffer
+
+	drawn, e := graph.ExportDOT(context.Background(), &document, DiagramOptions{
+		EdgeStyles: map[string]Style{ "coach": { "color": "red", "style": "bold" } },
+		NodeStyles: map[string]Style{ "coach": { "shape": "box" } },
+	}, NewQuery().WithSubject([]byte("Doc Rivers")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := `digraph simplegraph {
+	n0 [label="Doc Rivers"];
+	n1 [label="Clippers", shape="box"];
+	n2 [label="Celtics"];
+	n3 [label="Hawks"];
+	n0 -> n1 [label="coach", color="red", style="bold"];
+	n0 -> n2 [label="former coach"];
+	n0 -> n3 [label="former player"];
+}
+`
+
+	if drawn != 3 || document.String() != want {
+		t.Errorf("drew %v edges:\n%v\nwant\n%v", drawn, document.String(), want)
+	}
+}
+
+func TestSimpleGraph_ExportDOTPatterns(t *testing.T) {
+	graph := nbaGraph(t)
+	var document bytes.Buffer
+
+	// the teams Doc Rivers coached, and everyone else who played for them
+	patterns := []Query{
+		NewQuery().WithSubject([]byte("Doc Rivers")).WithPredicate([]byte("former coach")).WithObjectVariable("team"),
+		NewQuery().WithSubjectVariable("player").WithPredicate([]byte("former player")).WithObjectVariable("team"),
+	}
+
+	drawn, e := graph.ExportDOT(context.Background(), &document, DiagramOptions{}, patterns...)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// the coaching edge is shared by every result, but is only drawn once
+	if drawn != 4 || strings.Count(document.String(), `[label="former coach"]`) != 1 {
+		t.Errorf("drew %v edges:\n%v", drawn, document.String())
+	}
+
+	document.Reset()
+	drawn, e = graph.ExportDOT(context.Background(), &document, DiagramOptions{ MaxEdges: 2 }, patterns...)
+
+	if e != nil || drawn != 2 || !strings.Contains(document.String(), "// stopped after 2 edges") {
+		t.Errorf("drew %v edges: %v\n%v", drawn, e, document.String())
+	}
+}
+
+func TestSimpleGraph_ExportGraphML(t *testing.T) {
+	graph := nbaGraph(t)
+	var document bytes.Buffer
+
+	drawn, e := graph.ExportGraphML(context.Background(), &document, DiagramOptions{
+		MaxEdges:   2,
+		EdgeStyles: map[string]Style{ "coach": { "color": "red" } },
+	}, NewQuery().WithSubject([]byte("Doc Rivers")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="all" attr.name="label" attr.type="string"/>
+  <key id="edge.color" for="edge" attr.name="color" attr.type="string"/>
+  <graph id="simplegraph" edgedefault="directed">
+    <!-- stopped after 2 edges -->
+    <node id="n0"><data key="label">Doc Rivers</data></node>
+    <node id="n1"><data key="label">Clippers</data></node>
+    <node id="n2"><data key="label">Celtics</data></node>
+    <edge id="e0" source="n0" target="n1"><data key="label">coach</data><data key="edge.color">red</data></edge>
+    <edge id="e1" source="n0" target="n2"><data key="label">former coach</data></edge>
+  </graph>
+</graphml>
+`
+
+	if drawn != 2 || document.String() != want {
+		t.Errorf("drew %v edges:\n%v\nwant\n%v", drawn, document.String(), want)
+	}
+}
+
+func TestSimpleGraph_ExportDOTStylesNodesSeenAsSubjects(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+	_ = graph.AddEdges([]Edge{
+		NewEdge([]byte("Celtics"), []byte("based in"), []byte("Boston")),
+		NewEdge([]byte("Paul Pierce"), []byte("played for"), []byte("Celtics")),
+	})
+
+	var document bytes.Buffer
+
+	// spo order draws the Celtics as a subject before they're the object of played for
+	_, e := graph.ExportDOT(context.Background(), &document, DiagramOptions{
+		NodeStyles: map[string]Style{
+			"played for": { "shape": "box" },
+			"based in":   { "shape": "ellipse", "color": "grey" },
+		},
+	}, NewQuery())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := `digraph simplegraph {
+	n0 [label="Celtics", shape="box"];
+	n1 [label="Boston", color="grey", shape="ellipse"];
+	n2 [label="Paul Pierce"];
+	n0 -> n1 [label="based in"];
+	n2 -> n0 [label="played for"];
+}
+`
+
+	if document.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", document.String(), want)
+	}
+}
@@ -0,0 +1,200 @@
+package simplegraph
+
+import (
+	"context"
+	"fmt"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"strings"
+)
+
+// PlanNode is one step of a query plan, as described by Explain. Index scans are the leaves; sorts and joins consume
+// the nodes in Children, in order. A PlanNode renders as indented text with String, and as JSON with encoding/json.
+type PlanNode struct {
+	// Operator is one of "index scan", "sort", "merge join" or "hash join"
+	Operator string `json:"operator"`
+
+	// Index, Pattern and Range describe an index scan: the index read, the pattern it matches, and the keys it reads,
+	// from Range[0] inclusive to Range[1] exclusive
+	Index   string    `json:"index,omitempty"`
+	Pattern string    `json:"pattern,omitempty"`
+	Range   []string  `json:"range,omitempty"`
+
+	// JoinOn lists the variables a join matches its inputs on, and SortBy the variables a sort orders by
+	JoinOn []string `json:"join_on,omitempty"`
+	SortBy []string `json:"sort_by,omitempty"`
+
+	// Order lists the variables the node's output is sorted by, if any
+	Order []string `json:"order,omitempty"`
+
+	EstimatedRows float64 `json:"estimated_rows"`
+
+	// Estimates is set on the root, to "statistics" when the estimates come from the graph's Statistics, or "guesses"
+	// when it hasn't been analyzed and the planner falls back on fixed guesses
+	Estimates string `json:"estimates,omitempty"`
+
+	// Actual is what the node did when the query ran, filled in by ExplainAnalyze
+	Actual *OperatorMetrics `json:"actual,omitempty"`
+
+	Children []*PlanNode `json:"children,omitempty"`
+}
+
+// Explain returns the plan Match would run for the patterns, without running it
+func (graph *SimpleGraph) Explain(patterns ...Query) (*PlanNode, error) {
+	model := graph.costModel()
+	plan, e := model.generateQueryPlan(patterns...)
+
+	if e != nil {
+		return nil, e
+	}
+
+	node := plan.source.explain()
+	node.Estimates = model.estimateSource()
+
+	return node, nil
+}
+
+// ExplainAnalyze runs the query Match would for the patterns, discarding its results, and returns the plan along
+// with what each step of it actually did
+func (graph *SimpleGraph) ExplainAnalyze(ctx context.Context, patterns ...Query) (*PlanNode, error) {
+	model := graph.costModel()
+	model.analyze = true
+
+	plan, e := model.generateQueryPlan(patterns...)
+
+	if e != nil {
+		return nil, e
+	}
+
+	stream := graph.execute(ctx, plan)
+	defer stream.Close()
+
+	for range stream.Results() {
+	}
+
+	if e := stream.Err(); e != nil {
+		return nil, e
+	}
+
+	node := plan.source.explain()
+	node.Estimates = model.estimateSource()
+
+	return node, nil
+}
+
+func (node *PlanNode) String() string {
+	var builder strings.Builder
+	node.write(&builder, 0)
+
+	return builder.String()
+}
+
+func (node *PlanNode) write(builder *strings.Builder, depth int) {
+	switch node.Estimates {
+	case "statistics":
+		builder.WriteString("estimates from the graph's statistics\n")
+	case "guesses":
+		builder.WriteString("estimates are guesses, as the graph hasn't been analyzed\n")
+	}
+
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString(node.Operator)
+
+	if node.Index != "" {
+		fmt.Fprintf(builder, " %v %v range [%v, %v)", node.Index, node.Pattern, node.Range[0], node.Range[1])
+	}
+
+	if len(node.JoinOn) > 0 {
+		fmt.Fprintf(builder, " on %v", strings.Join(node.JoinOn, ", "))
+	}
+
+	if len(node.SortBy) > 0 {
+		fmt.Fprintf(builder, " by %v", strings.Join(node.SortBy, ", "))
+	}
+
+	if len(node.Order) > 0 {
+		fmt.Fprintf(builder, " ordered by %v", strings.Join(node.Order, ", "))
+	}
+
+	fmt.Fprintf(builder, " (estimated rows: %.4g", node.EstimatedRows)
+
+	if actual := node.Actual; actual != nil {
+		fmt.Fprintf(builder, "; actual rows: %v", actual.Rows)
+
+		if actual.KeysScanned > 0 {
+			fmt.Fprintf(builder, ", keys scanned: %v, bytes read: %v", actual.KeysScanned, actual.BytesRead)
+		}
+
+		if actual.PeakBuffered > 0 {
+			fmt.Fprintf(builder, ", peak buffered: %v", actual.PeakBuffered)
+		}
+
+		if actual.PeakBufferedBytes > 0 {
+			fmt.Fprintf(builder, " (%v bytes)", actual.PeakBufferedBytes)
+		}
+
+		fmt.Fprintf(builder, ", time: %v", actual.Duration)
+	}
+
+	builder.WriteString(")\n")
+
+	for _, child := range node.Children {
+		child.write(builder, depth + 1)
+	}
+}
+
+func (iss *indexScanSource) explain() *PlanNode {
+	span, _ := iss.idx.spanFor(*iss.query)
+	begin, end := span.keyRange()
+
+	return &PlanNode{
+		Operator:      "index scan",
+		Index:         iss.idx.name(),
+		Pattern:       iss.query.String(),
+		Range:         []string{ fdb.Printable(begin), fdb.Printable(end) },
+		Order:         iss.tripleOrder.variableOrder,
+		EstimatedRows: iss.estimate.rows,
+		Actual:        iss.metrics.snapshot(),
+	}
+}
+
+func (bss *bufferSortedSource) explain() *PlanNode {
+	return &PlanNode{
+		Operator:      "sort",
+		SortBy:        bss.tripleOrder.variableOrder,
+		Order:         bss.tripleOrder.variableOrder,
+		EstimatedRows: bss.getEstimate().rows,
+		Actual:        bss.metrics.snapshot(),
+		Children:      []*PlanNode{ bss.tripleSource.explain() },
+	}
+}
+
+func (lmj *mergeJoin) explain() *PlanNode {
+	node := &PlanNode{
+		Operator:      "merge join",
+		JoinOn:        lmj.joinOrder.variableOrder,
+		Order:         lmj.tripleOrder.variableOrder,
+		EstimatedRows: lmj.estimate.rows,
+		Actual:        lmj.metrics.snapshot(),
+	}
+
+	for _, source := range lmj.joins {
+		node.Children = append(node.Children, source.explain())
+	}
+
+	return node
+}
+
+func (hj *hashJoin) explain() *PlanNode {
+	node := &PlanNode{
+		Operator:      "hash join",
+		JoinOn:        hj.joinOrder.variableOrder,
+		EstimatedRows: hj.estimate.rows,
+		Actual:        hj.metrics.snapshot(),
+	}
+
+	for _, source := range hj.joins {
+		node.Children = append(node.Children, source.explain())
+	}
+
+	return node
+}
@@ -0,0 +1,99 @@
+package simplegraph
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSimpleGraph_Explain(t *testing.T) {
+	graph := friendsGraph()
+	friend := []byte("friend")
+
+	plan, e := graph.Explain(
+		NewQuery().WithSubject([]byte("paul")).WithPredicate(friend).WithObjectVariable("x"),
+		NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+		NewQuery().WithSubjectVariable("y").WithPredicate(friend).WithObject([]byte("jess")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := `estimates are guesses, as the graph hasn't been analyzed
+hash join on y (estimated rows: 100)
+  merge join on x ordered by x (estimated rows: 100)
+    index scan spo ("paul", "friend", ?x) range [\x02spo\x00\x01paul\x00\x01friend\x00, \x02spo\x00\x01paul\x00\x01friend\x01) ordered by x (estimated rows: 100)
+    index scan pso (?x, "friend", ?y) range [\x02pso\x00\x01friend\x00, \x02pso\x00\x01friend\x01) ordered by x, y (estimated rows: 1e+04)
+  index scan pos (?y, "friend", "jess") range [\x02pos\x00\x01friend\x00\x01jess\x00, \x02pos\x00\x01friend\x00\x01jess\x01) ordered by y (estimated rows: 100)
+`
+
+	if got := plan.String(); got != want {
+		t.Errorf("Explain() =\n%v\nwant\n%v", got, want)
+	}
+
+	encoded, e := json.Marshal(plan)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var decoded PlanNode
+	if e := json.Unmarshal(encoded, &decoded); e != nil {
+		t.Fatal(e)
+	}
+
+	if !reflect.DeepEqual(&decoded, plan) {
+		t.Errorf("expected the plan to round trip through JSON, got %s", encoded)
+	}
+
+	if _, e := graph.Explain(); e == nil {
+		t.Errorf("expected an error explaining an empty query")
+	}
+}
+
+func TestSimpleGraph_ExplainAnalyze(t *testing.T) {
+	graph := friendsGraph()
+	graph.SetSortMemoryBudget(1)
+
+	friend := []byte("friend")
+
+	plan, e := graph.ExplainAnalyze(context.Background(),
+		NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObjectVariable("c"),
+		NewQuery().WithSubjectVariable("y").WithPredicate([]byte("works at")).WithObjectVariable("d"))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// (?x friend ?y) merged with (?x works at ?c) on x, sorted by y, then merged with (?y works at ?d). a merge join
+	// stops reading once either side runs out, so only the final row count is certain, not those of its inputs
+	if plan.Operator != "merge join" || plan.Actual == nil || plan.Actual.Rows != 1 || plan.Actual.Duration <= 0 {
+		t.Fatalf("expected a final merge join producing 1 row, got\n%v", plan)
+	}
+
+	sort := plan.Children[0]
+	if sort.Operator != "sort" || sort.Actual.PeakBuffered != 1 || sort.Actual.PeakBufferedBytes == 0 {
+		t.Errorf("expected the sort to buffer 1 result at a time, got\n%v", sort)
+	}
+
+	plan, e = graph.ExplainAnalyze(context.Background(),
+		NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if actual := plan.Actual; actual.Rows != 10 || actual.KeysScanned != 10 || actual.BytesRead == 0 {
+		t.Errorf("expected the scan of friends to read 10 keys, got\n%v", plan)
+	}
+
+	if !strings.Contains(plan.String(), "actual rows: 10, keys scanned: 10") {
+		t.Errorf("expected the text plan to show actual rows, got\n%v", plan)
+	}
+
+	if _, e := graph.ExplainAnalyze(context.Background()); e == nil {
+		t.Errorf("expected an error analyzing an empty query")
+	}
+}
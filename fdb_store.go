@@ -1,33 +1,109 @@
 package simplegraph
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 )
 
+// ErrScanInterrupted is returned by a scan whose transaction failed after it had already streamed keys, which it
+// can't take back. FoundationDB limits transactions to 5 seconds, so it's usually a long scan or a slow reader.
+var ErrScanInterrupted = errors.New("the scan's transaction failed part way through")
+
 type FdbGraph struct {
 	db *fdb.Database
+
+	// resumableScans continues a failed scan in a new transaction, rather than failing with ErrScanInterrupted
+	resumableScans bool
+}
+
+func NewFdbGraph(db *fdb.Database) *FdbGraph {
+	return &FdbGraph{ db: db }
+}
+
+// SetResumableScans lets scans run past FoundationDB's 5 second transaction limit: when a scan's transaction fails,
+// it carries on from the last key it read in a new transaction. That gives up snapshot consistency, as each
+// transaction reads the database as of when it started, so a long scan can see some of the writes made while it runs
+// and miss others. Keys are still streamed once each, in order. By default scans read one snapshot, and fail with
+// ErrScanInterrupted instead.
+func (f *FdbGraph) SetResumableScans(resumable bool) {
+	f.resumableScans = resumable
+}
+
+func (f *FdbGraph) Get(ctx context.Context, prefix []byte, outputStream chan<- []byte) error {
+	prefixRange, e := fdb.PrefixRange(prefix)
+
+	if e != nil {
+		close(outputStream)
+		return e
+	}
+
+	return f.getRange(ctx, prefixRange.Begin.FDBKey(), prefixRange.End.FDBKey(), fdb.RangeOptions{}, outputStream)
+}
+
+func (f *FdbGraph) GetRange(ctx context.Context, begin, end []byte, outputStream chan<- []byte) error {
+	return f.Scan(ctx, begin, end, ScanOptions{}, outputStream)
 }
 
-func (f *FdbGraph) Get(prefix []byte, outputStream chan<- []byte) error {
+// Scan passes the limit and direction on to FDB, so a limited scan only reads as many keys as it returns
+func (f *FdbGraph) Scan(ctx context.Context, begin, end []byte, options ScanOptions, outputStream chan<- []byte) error {
+	return f.getRange(ctx, fdb.Key(begin), fdb.Key(end), options.fdbRangeOptions(), outputStream)
+}
+
+// getRange streams the keys from begin up to end. ReadTransact retries the read when its transaction fails, which
+// would stream the keys it had already sent again, so a retry either resumes after the last key sent or, for a
+// snapshot scan, gives up.
+func (f *FdbGraph) getRange(ctx context.Context, begin, end fdb.Key, options fdb.RangeOptions,
+	outputStream chan<- []byte) error {
 	defer close(outputStream)
 
+	var last fdb.Key
+	sent := 0
+
 	_, e := f.db.ReadTransact(func(transaction fdb.ReadTransaction) (i interface{}, e error) {
-		prefixRange, e := fdb.PrefixRange(prefix)
+		keyRange := fdb.KeyRange{ Begin: begin, End: end }
+		rangeOptions := options
+
+		if last != nil {
+			if options.Reverse {
+				keyRange.End = last
+			} else {
+				keyRange.Begin = append(append(fdb.Key{}, last...), 0x00)
+			}
 
-		if e != nil {
-			return nil, e
+			if options.Limit > 0 {
+				if sent >= options.Limit {
+					return nil, nil
+				}
+
+				rangeOptions.Limit = options.Limit - sent
+			}
 		}
 
-		rangeIterator := transaction.GetRange(prefixRange, fdb.RangeOptions{}).Iterator()
+		rangeIterator := transaction.GetRange(keyRange, rangeOptions).Iterator()
 
 		for rangeIterator.Advance() {
 			kv, e := rangeIterator.Get()
 
 			if e != nil {
+				if sent > 0 && !f.resumableScans {
+					return nil, fmt.Errorf("%w after %d keys: %v", ErrScanInterrupted, sent, e)
+				}
+
 				return nil, e
 			}
 
-			outputStream <- kv.Key
+			select {
+			case outputStream <- kv.Key:
+			case <-ctx.Done():
+				// not retryable, so this ends the transaction instead of looping in ReadTransact
+				return nil, ctx.Err()
+			}
+
+			last = kv.Key
+			sent++
 		}
 
 		return nil, nil
@@ -36,6 +112,20 @@ func (f *FdbGraph) Get(prefix []byte, outputStream chan<- []byte) error {
 	return e
 }
 
+func (options ScanOptions) fdbRangeOptions() fdb.RangeOptions {
+	rangeOptions := fdb.RangeOptions{
+		Limit:   options.Limit,
+		Reverse: options.Reverse,
+	}
+
+	// a limited scan is usually read in full, but an unlimited one may be abandoned early
+	if options.Limit > 0 {
+		rangeOptions.Mode = fdb.StreamingModeExact
+	}
+
+	return rangeOptions
+}
+
 func (f *FdbGraph) Put(keys ...[]byte) error {
 	emptyByte := make([]byte, 0)
 
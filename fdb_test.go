@@ -1,10 +1,13 @@
 package simplegraph
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 	"reflect"
 	"sync"
 	"testing"
@@ -15,9 +18,9 @@ func TestALotOfKeys(t *testing.T) {
 	_ = fdb.APIVersion(600)
 	database := fdb.MustOpenDefault()
 
-	graph := FdbGraph{&database}
+	graph := NewFdbGraph(&database)
 
-	simpleGraph := NewSimpleGraph(&graph)
+	simpleGraph := NewSimpleGraph(graph)
 
 	start := time.Now()
 
@@ -75,8 +78,8 @@ func TestALotOfKeys(t *testing.T) {
 func TestStreamingAnd(t *testing.T) {
 	_ = fdb.APIVersion(600)
 	database := fdb.MustOpenDefault()
-	graph := FdbGraph{&database}
-	simpleGraph := NewSimpleGraph(&graph)
+	graph := NewFdbGraph(&database)
+	simpleGraph := NewSimpleGraph(graph)
 
 	_, _ = database.Transact(func(tx fdb.Transaction) (i interface{}, e error) {
 		tx.ClearRange(subspace.AllKeys())
@@ -107,6 +110,7 @@ func TestStreamingAnd(t *testing.T) {
 	o : o p s | o s p           Timberwolves | played for | Garnett or Timberwolves | Garnett | played for
 	*/
 	edges, _ := simpleGraph.GetRangeStreamingAnd(
+		context.Background(),
 		Query{
 			predicate: []byte("played for"),
 			object: []byte("Timberwolves"),
@@ -119,7 +123,7 @@ func TestStreamingAnd(t *testing.T) {
 
 	var edgeResults []*Edge
 	k := 0
-	for edge := range edges {
+	for edge := range edges.Edges() {
 		edgeResults = append(edgeResults, edge)
 		k++
 	}
@@ -140,71 +144,78 @@ func TestStreamingAnd(t *testing.T) {
 func TestSimpleGraph_GetRangeStreamingAnd(t *testing.T) {
 	_ = fdb.APIVersion(600)
 	database := fdb.MustOpenDefault()
-	graph := FdbGraph{&database}
-	simpleGraph := NewSimpleGraph(&graph)
+	graph := NewFdbGraph(&database)
 
-	_ = simpleGraph.AddEdges([]Edge{
-		{subject: []byte("Paul Pierce"), predicate: []byte("played for"), object: []byte("Celtics"),},
-		{subject: []byte("Al Jefferson"), predicate: []byte("played for"), object: []byte("Celtics"),},
-		{subject: []byte("Al Jefferson"), predicate: []byte("played for"), object: []byte("Timberwolves"),},
-		{subject: []byte("Kevin Garnett"), predicate: []byte("played for"), object: []byte("Celtics"),},
-		{subject: []byte("Kevin Garnett"), predicate: []byte("played for"), object: []byte("Timberwolves"),},
-		{subject: []byte("Kyrie Irving"), predicate: []byte("played for"), object: []byte("Cleveland"),},
-		{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
-		{subject: []byte("Smush Parker"), predicate: []byte("played for"), object: []byte("Lakers"),},
-		{subject: []byte("Sasha Vujačić"), predicate: []byte("played for"), object: []byte("Lakers"),},
+	testGetRangeStreamingAnd(t, graph)
+}
+
+func TestFdbGraph_GetRange(t *testing.T) {
+	_ = fdb.APIVersion(600)
+	database := fdb.MustOpenDefault()
+
+	testStoreGetRange(t, NewFdbGraph(&database))
+}
+
+func TestFdbGraph_Scan(t *testing.T) {
+	_ = fdb.APIVersion(600)
+	database := fdb.MustOpenDefault()
+
+	testStoreScan(t, NewFdbGraph(&database))
+}
+
+// slowScan reads every key in the range, stalling after the first for longer than a transaction can live
+func slowScan(store *FdbGraph, begin, end []byte) ([][]byte, error) {
+	stream := make(chan []byte)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- store.GetRange(context.Background(), begin, end, stream)
+	}()
+
+	var keys [][]byte
+	for key := range stream {
+		if len(keys) == 0 {
+			time.Sleep(6 * time.Second)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, <-errs
+}
+
+func TestFdbGraph_ResumableScans(t *testing.T) {
+	_ = fdb.APIVersion(600)
+	database := fdb.MustOpenDefault()
+	store := NewFdbGraph(&database)
+	ss := subspace.Sub("resumable scans")
+
+	_, _ = database.Transact(func(tx fdb.Transaction) (i interface{}, e error) {
+		tx.ClearRange(ss)
+		return nil, nil
 	})
 
-	type args struct {
-		queries []Query
+	// enough keys that the first batch the iterator reads can't hold them all
+	var want [][]byte
+	for i := 0; i < 5000; i++ {
+		want = append(want, ss.Pack(tuple.Tuple{ int64(i) }))
 	}
 
-	tests := []struct {
-		name string
-		args args
-		want []*Edge
-	}{
-		{ "filters a single subject",
-			args{ []Query { { subject: []byte("Kyrie Irving") }, { subject: []byte("Kyrie Irving") } } },
-			[]*Edge {
-				{subject: []byte("Kyrie Irving"), predicate: []byte("played for"), object: []byte("Cleveland"),},
-				{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
-			},
-		},
-		{ "filters two apiece",
-			args{ []Query {
-				{ subject: []byte("Kyrie Irving"), predicate: []byte("plays for")},
-				{ subject: []byte("Kyrie Irving"), object: []byte("Celtics") } } },
-			[]*Edge {
-				{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
-			},
-		},
-		{ "filters player and team in separate queries",
-			args{ []Query { { subject: []byte("Kyrie Irving") }, { object: []byte("Celtics") } } },
-			[]*Edge {
-				{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
-			},
-		},
-		//{ "filters",
-		//	// TODO: Mark this as an invalid query
-		//	args{ []Query { { object: []byte("Lakers") }, { object: []byte("Celtics") } } },
-		//	[]*Edge {},
-		//},
+	_ = store.Put(want...)
+	begin, end := ss.FDBRangeKeys()
+
+	if _, e := slowScan(store, begin.FDBKey(), end.FDBKey()); !errors.Is(e, ErrScanInterrupted) {
+		t.Errorf("a snapshot scan got %v, want ErrScanInterrupted", e)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, _ := simpleGraph.GetRangeStreamingAnd(tt.args.queries[0], tt.args.queries[1])
+	store.SetResumableScans(true)
+	got, e := slowScan(store, begin.FDBKey(), end.FDBKey())
 
-			var edgeResults []*Edge
-			for edge := range got {
-				edgeResults = append(edgeResults, edge)
-			}
+	if e != nil {
+		t.Fatal(e)
+	}
 
-			if !reflect.DeepEqual(edgeResults, tt.want) {
-				t.Errorf("simpleGraph.GetRangeStreamingAnd() = %v, want %v", edgeResults, tt.want)
-			}
-		})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("a resumable scan got %v keys, want each of the %v once, in order", len(got), len(want))
 	}
 }
-
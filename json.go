@@ -0,0 +1,331 @@
+package simplegraph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// Edges and search results are written to JSON losslessly. A []byte that's valid UTF-8 is a JSON string, and any
+// other is {"base64": "..."}. Integers and booleans are JSON literals, floats are numbers that always have a fraction
+// or an exponent, and the other object types are tagged: {"string": "..."}, {"time": "<RFC 3339>"} and
+// {"uuid": "..."}. Floats JSON can't hold are tagged too, as {"float": "NaN"}, "+Inf" or "-Inf".
+
+// MarshalJSON writes the edge as {"s": subject, "p": predicate, "o": object}
+func (edge Edge) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(`{"s":`)
+	_ = writeJSONValue(&buffer, edge.subject)
+	buffer.WriteString(`,"p":`)
+	_ = writeJSONValue(&buffer, edge.predicate)
+	buffer.WriteString(`,"o":`)
+
+	if e := writeJSONValue(&buffer, edge.object); e != nil {
+		return nil, e
+	}
+
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func (edge *Edge) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		S, P, O json.RawMessage
+	}
+
+	if e := json.Unmarshal(data, &fields); e != nil {
+		return e
+	}
+
+	if fields.S == nil || fields.P == nil || fields.O == nil {
+		return errors.New("an edge needs all of s, p and o")
+	}
+
+	subject, e := readJSONBytes(fields.S)
+
+	if e != nil {
+		return fmt.Errorf("s: %v", e)
+	}
+
+	predicate, e := readJSONBytes(fields.P)
+
+	if e != nil {
+		return fmt.Errorf("p: %v", e)
+	}
+
+	object, e := readJSONValue(fields.O)
+
+	if e != nil {
+		return fmt.Errorf("o: %v", e)
+	}
+
+	*edge = NewEdge(subject, predicate, object)
+	return nil
+}
+
+// MarshalJSON writes the result as {"bindings": {variable: value, ...}, "edges": [edge, ...]}
+func (sr *SearchResults) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(`{"bindings":{`)
+
+	names := make([]string, 0, len(sr.variables))
+	for name := range sr.variables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for i, name := range names {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+
+		quoted, _ := json.Marshal(name)
+		buffer.Write(quoted)
+		buffer.WriteByte(':')
+
+		if e := writeJSONValue(&buffer, sr.variables[name].value); e != nil {
+			return nil, e
+		}
+	}
+
+	buffer.WriteString(`},"edges":`)
+	edges, e := json.Marshal(sr.edges)
+
+	if e != nil {
+		return nil, e
+	}
+
+	buffer.Write(edges)
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func (sr *SearchResults) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Bindings map[string]json.RawMessage
+		Edges    []*Edge
+	}
+
+	if e := json.Unmarshal(data, &fields); e != nil {
+		return e
+	}
+
+	variables := make(map[string]*VariableResult, len(fields.Bindings))
+
+	for name, raw := range fields.Bindings {
+		value, e := readJSONValue(raw)
+
+		if e != nil {
+			return fmt.Errorf("%v: %v", name, e)
+		}
+
+		variables[name] = &VariableResult{ name: name, value: value }
+	}
+
+	sr.edges, sr.variables = fields.Edges, variables
+	return nil
+}
+
+func writeJSONValue(buffer *bytes.Buffer, value interface{}) error {
+	tagged := func(tag, text string) {
+		buffer.WriteString(`{"` + tag + `":`)
+		quoted, _ := json.Marshal(text)
+		buffer.Write(quoted)
+		buffer.WriteByte('}')
+	}
+
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteString(`""`)
+	case []byte:
+		if utf8.Valid(v) {
+			quoted, _ := json.Marshal(string(v))
+			buffer.Write(quoted)
+		} else {
+			tagged("base64", base64.StdEncoding.EncodeToString(v))
+		}
+	case string:
+		tagged("string", v)
+	case int:
+		buffer.WriteString(strconv.Itoa(v))
+	case int64:
+		buffer.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			tagged("float", strconv.FormatFloat(v, 'g', -1, 64))
+			break
+		}
+
+		number := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(number, ".e") {
+			number += ".0"
+		}
+
+		buffer.WriteString(number)
+	case bool:
+		buffer.WriteString(strconv.FormatBool(v))
+	case time.Time:
+		tagged("time", v.UTC().Format(time.RFC3339Nano))
+	case tuple.UUID:
+		lexical, _ := literalForm(v)
+		tagged("uuid", lexical)
+	default:
+		return validateValue(value)
+	}
+
+	return nil
+}
+
+// readJSONBytes reads a subject or predicate, which can only be []byte
+func readJSONBytes(raw json.RawMessage) ([]byte, error) {
+	value, e := readJSONValue(raw)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if field, ok := value.([]byte); ok {
+		return field, nil
+	}
+
+	return nil, fmt.Errorf("expected a string or base64, found %s", raw)
+}
+
+func readJSONValue(raw json.RawMessage) (interface{}, error) {
+	raw = bytes.TrimSpace(raw)
+
+	if len(raw) == 0 {
+		return nil, errors.New("missing value")
+	}
+
+	switch raw[0] {
+	case '"':
+		var text string
+		e := json.Unmarshal(raw, &text)
+		return []byte(text), e
+	case 't', 'f':
+		var value bool
+		e := json.Unmarshal(raw, &value)
+		return value, e
+	case '{':
+		return readTaggedJSONValue(raw)
+	case 'n':
+		return nil, errors.New("null isn't a value")
+	case '[':
+		return nil, errors.New("an array isn't a value")
+	}
+
+	number := string(raw)
+
+	if strings.ContainsAny(number, ".eE") {
+		return strconv.ParseFloat(number, 64)
+	}
+
+	return strconv.ParseInt(number, 10, 64)
+}
+
+func readTaggedJSONValue(raw json.RawMessage) (interface{}, error) {
+	var tags map[string]string
+
+	if e := json.Unmarshal(raw, &tags); e != nil || len(tags) != 1 {
+		return nil, fmt.Errorf("expected a value tagged with its type, found %s", raw)
+	}
+
+	for tag, text := range tags {
+		switch tag {
+		case "base64":
+			return base64.StdEncoding.DecodeString(text)
+		case "string":
+			return text, nil
+		case "float":
+			return strconv.ParseFloat(text, 64)
+		case "time":
+			t, e := time.Parse(time.RFC3339Nano, text)
+			return t.UTC(), e
+		case "uuid":
+			return parseUUID(text)
+		}
+	}
+
+	return nil, fmt.Errorf("unknown value type in %s", raw)
+}
+
+func parseUUID(text string) (tuple.UUID, error) {
+	var uuid tuple.UUID
+	decoded, e := hex.DecodeString(strings.Replace(text, "-", "", -1))
+
+	if e != nil || len(decoded) != len(uuid) {
+		return uuid, fmt.Errorf("invalid UUID %q", text)
+	}
+
+	copy(uuid[:], decoded)
+	return uuid, nil
+}
+
+// JSONLinesEncoder writes edges or search results as JSON Lines, one JSON object per line
+type JSONLinesEncoder struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func NewJSONLinesEncoder(w io.Writer) *JSONLinesEncoder {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetEscapeHTML(false)
+
+	return &JSONLinesEncoder{ w: buffered, encoder: encoder }
+}
+
+func (je *JSONLinesEncoder) EncodeEdge(edge Edge) error {
+	return je.encoder.Encode(edge)
+}
+
+func (je *JSONLinesEncoder) EncodeResult(result *SearchResults) error {
+	return je.encoder.Encode(result)
+}
+
+// Flush writes any buffered lines to the underlying writer
+func (je *JSONLinesEncoder) Flush() error {
+	return je.w.Flush()
+}
+
+// JSONLinesDecoder reads edges or search results from JSON Lines. Decode returns io.EOF once the input is exhausted.
+type JSONLinesDecoder struct {
+	decoder *json.Decoder
+}
+
+func NewJSONLinesDecoder(r io.Reader) *JSONLinesDecoder {
+	return &JSONLinesDecoder{ decoder: json.NewDecoder(r) }
+}
+
+func (jd *JSONLinesDecoder) DecodeEdge() (Edge, error) {
+	var edge Edge
+	e := jd.decoder.Decode(&edge)
+	return edge, e
+}
+
+func (jd *JSONLinesDecoder) DecodeResult() (*SearchResults, error) {
+	result := &SearchResults{}
+
+	if e := jd.decoder.Decode(result); e != nil {
+		return nil, e
+	}
+
+	return result, nil
+}
@@ -0,0 +1,255 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+func TestEdge_JSON(t *testing.T) {
+	s, p := []byte("paul"), []byte("has")
+
+	tests := []struct {
+		name string
+		edge Edge
+		want string
+	}{
+		{"it writes UTF-8 bytes as strings", NewEdge(s, p, []byte("Celtics ☘")), `{"s":"paul","p":"has","o":"Celtics ☘"}`},
+		{"it writes other bytes as base64", NewEdge([]byte{ 0xff, 0x00 }, p, []byte{ 0xfe }),
+			`{"s":{"base64":"/wA="},"p":"has","o":{"base64":"/g=="}}`},
+		{"it tags strings", NewEdge(s, p, "Celtics"), `{"s":"paul","p":"has","o":{"string":"Celtics"}}`},
+		{"it writes integers", NewEdge(s, p, int64(-34)), `{"s":"paul","p":"has","o":-34}`},
+		{"it always writes floats with a fraction", NewEdge(s, p, 2.0), `{"s":"paul","p":"has","o":2.0}`},
+		{"it writes large floats", NewEdge(s, p, 1e300), `{"s":"paul","p":"has","o":1e+300}`},
+		{"it tags floats JSON can't hold", NewEdge(s, p, math.Inf(-1)), `{"s":"paul","p":"has","o":{"float":"-Inf"}}`},
+		{"it writes booleans", NewEdge(s, p, true), `{"s":"paul","p":"has","o":true}`},
+		{"it tags times", NewEdge(s, p, time.Date(1998, time.June, 24, 1, 2, 3, 4, time.UTC)),
+			`{"s":"paul","p":"has","o":{"time":"1998-06-24T01:02:03.000000004Z"}}`},
+		{"it tags UUIDs", NewEdge(s, p, tuple.UUID{ 0x12, 0x34, 15: 0xff }),
+			`{"s":"paul","p":"has","o":{"uuid":"12340000-0000-0000-0000-0000000000ff"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, e := json.Marshal(tt.edge)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+
+			var decoded Edge
+			if e := json.Unmarshal(got, &decoded); e != nil {
+				t.Fatal(e)
+			}
+
+			if !reflect.DeepEqual(decoded, tt.edge) {
+				t.Errorf("got %#v, want %#v", decoded, tt.edge)
+			}
+		})
+	}
+
+	var edge Edge
+	for _, malformed := range []string{ `{"s":"a","p":"b"}`, `{"s":1,"p":"b","o":"c"}`, `{"s":"a","p":"b","o":{"colour":"red"}}`,
+		`{"s":"a","p":"b","o":null}` } {
+		if e := json.Unmarshal([]byte(malformed), &edge); e == nil {
+			t.Errorf("%v was accepted", malformed)
+		}
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	graph := seasonsGraph()
+	var lines bytes.Buffer
+	encoder := NewJSONLinesEncoder(&lines)
+
+	results, e := graph.Match(context.Background(),
+		NewQuery().WithSubjectVariable("player").WithPredicate([]byte("team")).WithObject("Celtics"),
+		NewQuery().WithSubjectVariable("player").WithPredicate([]byte("seasons played")).WithObjectVariable("seasons"))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var want []*SearchResults
+	for result := range results.Results() {
+		want = append(want, result)
+
+		if e := encoder.EncodeResult(result); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if e := encoder.Flush(); e != nil {
+		t.Fatal(e)
+	}
+
+	first := strings.SplitN(lines.String(), "\n", 2)[0]
+	if !strings.HasPrefix(first, `{"bindings":{"player":"kevin mchale","seasons":13},"edges":[{"s":"kevin mchale"`) {
+		t.Errorf("got %v", first)
+	}
+
+	decoder := NewJSONLinesDecoder(&lines)
+	var got []*SearchResults
+
+	for {
+		result, e := decoder.DecodeResult()
+
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			t.Fatal(e)
+		}
+
+		got = append(got, result)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func sortEdges(edges []Edge) []Edge {
+	sort.Slice(edges, func(i, j int) bool {
+		return bytes.Compare(Indices["spo"].toBytes(&edges[i]), Indices["spo"].toBytes(&edges[j])) < 0
+	})
+
+	return edges
+}
+
+func TestJSONLD(t *testing.T) {
+	graph := rdfGraph()
+	var document bytes.Buffer
+	encoder := NewJSONLDEncoder(&document, map[string]string{ "nba": "http://nba.example/", "xsd": xsdNamespace })
+
+	for _, edge := range queriedEdges(t, graph, NewQuery().WithSubject([]byte("http://nba.example/paul-pierce"))) {
+		if e := encoder.EncodeEdge(edge); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if e := encoder.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	want := `{
+  "@context": {
+    "nba": "http://nba.example/",
+    "xsd": "http://www.w3.org/2001/XMLSchema#"
+  },
+  "@graph": [
+    {
+      "@id": "nba:paul-pierce",
+      "@type": "nba:Player",
+      "nba:drafted": {
+        "@type": "xsd:dateTime",
+        "@value": "1998-06-24T00:00:00Z"
+      },
+      "nba:name": "Paul \"The Truth\" Pierce\n",
+      "nba:playedFor": [
+        {
+          "@id": "nba:Celtics"
+        },
+        {
+          "@id": "nba:Nets"
+        }
+      ],
+      "nba:ppg": {
+        "@type": "xsd:double",
+        "@value": "1.97E+01"
+      },
+      "nba:retired": true,
+      "nba:seasons": 19
+    }
+  ]
+}
+`
+
+	if document.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", document.String(), want)
+	}
+
+	decoded, e := DecodeJSONLD(&document)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	original := queriedEdges(t, graph, NewQuery().WithSubject([]byte("http://nba.example/paul-pierce")))
+	if !reflect.DeepEqual(sortEdges(decoded), sortEdges(original)) {
+		t.Errorf("got %v, want %v", decoded, original)
+	}
+}
+
+func TestDecodeJSONLD(t *testing.T) {
+	document := `[{
+		"@context": { "name": "http://schema.org/name", "knows": { "@id": "http://schema.org/knows" } },
+		"@id": "http://nba.example/paul",
+		"name": ["Paul", { "@value": "Pierce", "@language": "en" }],
+		"knows": { "name": "Kevin" },
+		"http://nba.example/photo": { "@value": "/w==", "@type": "http://www.w3.org/2001/XMLSchema#base64Binary" }
+	}]`
+
+	edges, e := DecodeJSONLD(strings.NewReader(document))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	paul := []byte("http://nba.example/paul")
+	name := []byte("http://schema.org/name")
+
+	if len(edges) != 5 {
+		t.Fatalf("got %v", edges)
+	}
+
+	kevin := edges[1].subject
+	want := []Edge{
+		NewEdge(paul, []byte("http://nba.example/photo"), []byte{ 0xff }),
+		NewEdge(kevin, name, "Kevin"),
+		NewEdge(paul, []byte("http://schema.org/knows"), kevin),
+		NewEdge(paul, name, "Paul"),
+		NewEdge(paul, name, "Pierce"),
+	}
+
+	if !reflect.DeepEqual(edges, want) || !isBlankNode(kevin) {
+		t.Errorf("got %v, want %v", edges, want)
+	}
+}
+
+func TestDecodeJSONLD_ScopesBlankNodes(t *testing.T) {
+	document := `[
+		{ "@id": "_:b0", "http://schema.org/name": "Kevin" },
+		{ "@id": "http://nba.example/paul", "http://schema.org/knows": { "@id": "_:b0" } }
+	]`
+
+	first, e := DecodeJSONLD(strings.NewReader(document))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	second, e := DecodeJSONLD(strings.NewReader(document))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// a label names the same node throughout a document, and a different one in the next
+	kevin := first[0].subject
+	if !isBlankNode(kevin) || !bytes.Equal(first[1].object.([]byte), kevin) || bytes.Equal(second[0].subject, kevin) {
+		t.Errorf("got %v and %v", first, second)
+	}
+}
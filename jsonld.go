@@ -0,0 +1,370 @@
+package simplegraph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const xsdBase64Binary = xsdNamespace + "base64Binary"
+
+// JSONLDEncoder writes edges as a compact JSON-LD document, with a node object for each subject, and edges mapped to
+// RDF the way TurtleWriter maps them. Its context maps prefixes, or terms, to the IRIs they stand for, and is used to
+// shorten IRIs. Nodes have to be gathered from every edge before any can be written, so the document is only
+// written on Close.
+type JSONLDEncoder struct {
+	w       io.Writer
+	context map[string]string
+
+	// terms are tried longest IRI first, so the most specific wins
+	terms    []string
+	nodes    map[string]map[string][]interface{}
+	subjects []string
+}
+
+func NewJSONLDEncoder(w io.Writer, context map[string]string) *JSONLDEncoder {
+	je := &JSONLDEncoder{ w: w, context: context, nodes: make(map[string]map[string][]interface{}) }
+
+	for term := range context {
+		je.terms = append(je.terms, term)
+	}
+
+	sort.Slice(je.terms, func(i, j int) bool {
+		a, b := context[je.terms[i]], context[je.terms[j]]
+
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+
+		return je.terms[i] < je.terms[j]
+	})
+
+	return je
+}
+
+func (je *JSONLDEncoder) EncodeEdge(edge Edge) error {
+	if !utf8.Valid(edge.subject) || !utf8.Valid(edge.predicate) {
+		return fmt.Errorf("%v: JSON-LD subjects and predicates must be UTF-8", edge)
+	}
+
+	subject := je.compact(string(edge.subject))
+	node, ok := je.nodes[subject]
+
+	if !ok {
+		node = make(map[string][]interface{})
+		je.nodes[subject] = node
+		je.subjects = append(je.subjects, subject)
+	}
+
+	if string(edge.predicate) == rdfType {
+		if resource, ok := edge.object.([]byte); ok && utf8.Valid(resource) {
+			node["@type"] = append(node["@type"], je.compact(string(resource)))
+			return nil
+		}
+	}
+
+	value, e := je.value(edge.object)
+
+	if e != nil {
+		return e
+	}
+
+	predicate := je.compact(string(edge.predicate))
+	node[predicate] = append(node[predicate], value)
+	return nil
+}
+
+// EncodeResult adds the edges the result matched
+func (je *JSONLDEncoder) EncodeResult(result *SearchResults) error {
+	for _, edge := range result.edges {
+		if e := je.EncodeEdge(*edge); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Close writes the document. It doesn't close the underlying writer.
+func (je *JSONLDEncoder) Close() error {
+	graph := make([]map[string]interface{}, 0, len(je.subjects))
+
+	for _, subject := range je.subjects {
+		node := map[string]interface{}{ "@id": subject }
+
+		for key, values := range je.nodes[subject] {
+			if len(values) == 1 {
+				node[key] = values[0]
+			} else {
+				node[key] = values
+			}
+		}
+
+		graph = append(graph, node)
+	}
+
+	document := map[string]interface{}{ "@graph": graph }
+	if len(je.context) > 0 {
+		document["@context"] = je.context
+	}
+
+	encoder := json.NewEncoder(je.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(document)
+}
+
+func (je *JSONLDEncoder) value(object interface{}) (interface{}, error) {
+	switch v := object.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return map[string]string{ "@id": je.compact(string(v)) }, nil
+		}
+
+		return map[string]string{ "@value": base64.StdEncoding.EncodeToString(v), "@type": je.compact(xsdBase64Binary) }, nil
+	case string, bool:
+		return v, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+
+	if e := validateValue(object); e != nil {
+		return nil, e
+	}
+
+	lexical, datatype := literalForm(object)
+
+	if datatype == "" {
+		return lexical, nil
+	}
+
+	return map[string]string{ "@value": lexical, "@type": je.compact(datatype) }, nil
+}
+
+// compact shortens an IRI to a term or prefixed name from the context, where one fits
+func (je *JSONLDEncoder) compact(iri string) string {
+	for _, term := range je.terms {
+		namespace := je.context[term]
+
+		if iri == namespace {
+			return term
+		}
+
+		if local := strings.TrimPrefix(iri, namespace); local != iri && local != "" && !strings.HasPrefix(local, "/") {
+			return term + ":" + local
+		}
+	}
+
+	return iri
+}
+
+// DecodeJSONLD reads the edges in a JSON-LD document: a node object, an array of them, or an object with a @graph.
+// It understands contexts that map terms and prefixes to IRIs, @id, @type, @value and nested node objects, which
+// without an @id become blank nodes. Blank node labels are scoped to the document, as in TurtleReader. Values map onto objects as in TurtleReader, and xsd:base64Binary values become
+// []byte.
+func DecodeJSONLD(r io.Reader) ([]Edge, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var document interface{}
+	if e := decoder.Decode(&document); e != nil {
+		return nil, e
+	}
+
+	jd := &jsonLDDecoder{ context: make(map[string]string), scope: blankNodeScope() }
+
+	switch top := document.(type) {
+	case []interface{}:
+		if e := jd.nodes(top); e != nil {
+			return nil, e
+		}
+	case map[string]interface{}:
+		jd.readContext(top)
+
+		if graph, ok := top["@graph"].([]interface{}); ok {
+			if e := jd.nodes(graph); e != nil {
+				return nil, e
+			}
+		} else if _, e := jd.node(top); e != nil {
+			return nil, e
+		}
+	default:
+		return nil, errors.New("expected a JSON-LD object or array")
+	}
+
+	return jd.edges, nil
+}
+
+type jsonLDDecoder struct {
+	context   map[string]string
+	edges     []Edge
+	scope     string
+	anonymous int
+}
+
+func (jd *jsonLDDecoder) readContext(object map[string]interface{}) {
+	context, _ := object["@context"].(map[string]interface{})
+
+	for term, definition := range context {
+		switch d := definition.(type) {
+		case string:
+			jd.context[term] = d
+		case map[string]interface{}:
+			if id, ok := d["@id"].(string); ok {
+				jd.context[term] = id
+			}
+		}
+	}
+}
+
+func (jd *jsonLDDecoder) expand(term string) string {
+	if iri, ok := jd.context[term]; ok {
+		return iri
+	}
+
+	if parts := strings.SplitN(term, ":", 2); len(parts) == 2 && !strings.HasPrefix(parts[1], "//") {
+		if namespace, ok := jd.context[parts[0]]; ok {
+			return namespace + parts[1]
+		}
+	}
+
+	return term
+}
+
+// id expands an @id, scoping a blank node label to the document as TurtleReader does
+func (jd *jsonLDDecoder) id(id string) []byte {
+	if strings.HasPrefix(id, "_:") {
+		return []byte(id + "_" + jd.scope)
+	}
+
+	return []byte(jd.expand(id))
+}
+
+func (jd *jsonLDDecoder) nodes(nodes []interface{}) error {
+	for _, element := range nodes {
+		node, ok := element.(map[string]interface{})
+
+		if !ok {
+			return fmt.Errorf("expected a node object, found %v", element)
+		}
+
+		if _, e := jd.node(node); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// node adds the node's edges, and returns its IRI or blank node label
+func (jd *jsonLDDecoder) node(node map[string]interface{}) ([]byte, error) {
+	// contexts are meant to be scoped to the object that holds them, but are simply added to what's known here
+	jd.readContext(node)
+
+	var subject []byte
+
+	if id, ok := node["@id"].(string); ok {
+		subject = jd.id(id)
+	} else {
+		jd.anonymous++
+		subject = []byte("_:anon" + jd.scope + "x" + strconv.Itoa(jd.anonymous))
+	}
+
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values, ok := node[key].([]interface{})
+
+		if !ok {
+			values = []interface{}{ node[key] }
+		}
+
+		switch key {
+		case "@id", "@context":
+			continue
+		case "@type":
+			for _, value := range values {
+				class, ok := value.(string)
+
+				if !ok {
+					return nil, fmt.Errorf("expected a type IRI, found %v", value)
+				}
+
+				jd.edges = append(jd.edges, NewEdge(subject, []byte(rdfType), []byte(jd.expand(class))))
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(key, "@") {
+			return nil, fmt.Errorf("%v isn't supported", key)
+		}
+
+		predicate := []byte(jd.expand(key))
+
+		for _, value := range values {
+			object, e := jd.value(value)
+
+			if e != nil {
+				return nil, fmt.Errorf("%v: %v", key, e)
+			}
+
+			jd.edges = append(jd.edges, NewEdge(subject, predicate, object))
+		}
+	}
+
+	return subject, nil
+}
+
+func (jd *jsonLDDecoder) value(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string, bool:
+		return v, nil
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return v.Float64()
+		}
+
+		return v.Int64()
+	case map[string]interface{}:
+		literal, ok := v["@value"]
+
+		if !ok {
+			if id, ok := v["@id"].(string); ok && len(v) == 1 {
+				return jd.id(id), nil
+			}
+
+			return jd.node(v)
+		}
+
+		datatype, _ := v["@type"].(string)
+		datatype = jd.expand(datatype)
+
+		lexical, ok := literal.(string)
+		if !ok {
+			return jd.value(literal)
+		}
+
+		if datatype == xsdBase64Binary {
+			return base64.StdEncoding.DecodeString(lexical)
+		}
+
+		return literalValue(lexical, datatype)
+	default:
+		return nil, fmt.Errorf("%v isn't a value", value)
+	}
+}
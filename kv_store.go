@@ -1,7 +1,94 @@
 package simplegraph
 
+import "context"
+
+// KVStore is an ordered, key-only store the hexastore indices are written to.
+//
+// Get streams every key that starts with prefix, in ascending order, and closes stream before returning. It must stop
+// and return ctx.Err() once ctx is done, releasing any read it holds open. GetRange does the same for every key from
+// begin, inclusive, to end, exclusive.
 type KVStore interface {
-	Get(prefix []byte, stream chan<- []byte) error
+	Get(ctx context.Context, prefix []byte, stream chan<- []byte) error
+	GetRange(ctx context.Context, begin, end []byte, stream chan<- []byte) error
 	Put(keys ... []byte) error
 	Delete(keys ... []byte) error
 }
+
+// ScanOptions narrow a scan of a key range
+type ScanOptions struct {
+	// Limit stops the scan after this many keys. Zero means no limit.
+	Limit int
+
+	// Reverse scans from the end of the range back to its beginning
+	Reverse bool
+}
+
+// RangeScanner is implemented by stores that can limit and reverse scans themselves. Scan streams the keys from begin,
+// inclusive, to end, exclusive, in the order and up to the limit given, with the same contract as Get.
+type RangeScanner interface {
+	Scan(ctx context.Context, begin, end []byte, options ScanOptions, stream chan<- []byte) error
+}
+
+// scan reads a key range from any store, using its RangeScanner if it has one. Otherwise the limit is applied as
+// keys arrive, and a reverse scan holds the whole range in memory to turn it around.
+func scan(ctx context.Context, store KVStore, begin, end []byte, options ScanOptions, stream chan<- []byte) error {
+	if scanner, ok := store.(RangeScanner); ok {
+		return scanner.Scan(ctx, begin, end, options, stream)
+	}
+
+	defer close(stream)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan []byte)
+	storeError := make(chan error, 1)
+
+	go func() {
+		storeError <- store.GetRange(ctx, begin, end, keys)
+	}()
+
+	var buffered [][]byte
+	count := 0
+
+	for key := range keys {
+		if options.Reverse {
+			buffered = append(buffered, key)
+			continue
+		}
+
+		select {
+		case stream <- key:
+			count++
+		case <-ctx.Done():
+			for range keys {
+			}
+
+			return ctx.Err()
+		}
+
+		if count == options.Limit {
+			// stop the store early. it closes keys once it sees the cancellation
+			cancel()
+			for range keys {
+			}
+
+			return nil
+		}
+	}
+
+	if e := <-storeError; e != nil {
+		return e
+	}
+
+	for i := len(buffered) - 1; i >= 0 && (options.Limit == 0 || count < options.Limit); i-- {
+		select {
+		case stream <- buffered[i]:
+			count++
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
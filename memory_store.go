@@ -0,0 +1,141 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+const memoryGraphDegree = 32
+
+// MemoryGraph is an in-process, ordered KVStore backed by a copy-on-write B-tree. Reads are served from a snapshot
+// of the tree taken when Get is called, so a slow consumer never blocks writers and never observes a partial Put.
+type MemoryGraph struct {
+	mu   sync.Mutex
+	tree *btree.BTree
+}
+
+func NewMemoryGraph() *MemoryGraph {
+	return &MemoryGraph{
+		tree: btree.New(memoryGraphDegree),
+	}
+}
+
+type memoryKey []byte
+
+func (k memoryKey) Less(than btree.Item) bool {
+	return bytes.Compare(k, than.(memoryKey)) < 0
+}
+
+func (m *MemoryGraph) snapshot() *btree.BTree {
+	// Clone mutates the copy-on-write context of the source tree, so it needs the exclusive lock
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tree.Clone()
+}
+
+func (m *MemoryGraph) Get(ctx context.Context, prefix []byte, outputStream chan<- []byte) error {
+	return m.ascend(ctx, prefix, func(key []byte) bool {
+		return bytes.HasPrefix(key, prefix)
+	}, outputStream)
+}
+
+func (m *MemoryGraph) GetRange(ctx context.Context, begin, end []byte, outputStream chan<- []byte) error {
+	return m.ascend(ctx, begin, func(key []byte) bool {
+		return bytes.Compare(key, end) < 0
+	}, outputStream)
+}
+
+func (m *MemoryGraph) Scan(ctx context.Context, begin, end []byte, options ScanOptions,
+	outputStream chan<- []byte) error {
+	count := 0
+	within := func(key []byte) bool {
+		count++
+		return options.Limit == 0 || count <= options.Limit
+	}
+
+	if !options.Reverse {
+		return m.ascend(ctx, begin, func(key []byte) bool {
+			return bytes.Compare(key, end) < 0 && within(key)
+		}, outputStream)
+	}
+
+	defer close(outputStream)
+
+	var e error
+
+	m.snapshot().DescendLessOrEqual(memoryKey(end), func(item btree.Item) bool {
+		key := item.(memoryKey)
+
+		// end itself is excluded
+		if bytes.Equal(key, end) {
+			return true
+		}
+
+		if bytes.Compare(key, begin) < 0 || !within(key) {
+			return false
+		}
+
+		select {
+		case outputStream <- key:
+			return true
+		case <-ctx.Done():
+			e = ctx.Err()
+			return false
+		}
+	})
+
+	return e
+}
+
+// ascend streams keys from begin onwards for as long as they're within the scan
+func (m *MemoryGraph) ascend(ctx context.Context, begin []byte, within func(key []byte) bool,
+	outputStream chan<- []byte) error {
+	defer close(outputStream)
+
+	var e error
+
+	m.snapshot().AscendGreaterOrEqual(memoryKey(begin), func(item btree.Item) bool {
+		key := item.(memoryKey)
+
+		if !within(key) {
+			return false
+		}
+
+		select {
+		case outputStream <- key:
+			return true
+		case <-ctx.Done():
+			e = ctx.Err()
+			return false
+		}
+	})
+
+	return e
+}
+
+func (m *MemoryGraph) Put(keys ...[]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		// keys are retained, so don't alias the caller's buffer
+		m.tree.ReplaceOrInsert(memoryKey(append([]byte(nil), key...)))
+	}
+
+	return nil
+}
+
+func (m *MemoryGraph) Delete(keys ...[]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		m.tree.Delete(memoryKey(key))
+	}
+
+	return nil
+}
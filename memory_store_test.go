@@ -0,0 +1,61 @@
+package simplegraph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryGraph_Get(t *testing.T) {
+	store := NewMemoryGraph()
+
+	_ = store.Put([]byte("b/2"), []byte("a/1"), []byte("b/1"), []byte("c/1"), []byte("b/3"))
+	_ = store.Delete([]byte("b/3"))
+
+	tests := []struct {
+		name   string
+		prefix []byte
+		want   [][]byte
+	}{
+		{"it scans a prefix in order", []byte("b/"), [][]byte{[]byte("b/1"), []byte("b/2")}},
+		{"it scans everything with an empty prefix", []byte{}, [][]byte{[]byte("a/1"), []byte("b/1"), []byte("b/2"), []byte("c/1")}},
+		{"it returns nothing for a missing prefix", []byte("d/"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := make(chan []byte)
+
+			go func() {
+				if e := store.Get(context.Background(), tt.prefix, stream); e != nil {
+					t.Errorf("MemoryGraph.Get() error = %v", e)
+				}
+			}()
+
+			var got [][]byte
+			for key := range stream {
+				got = append(got, key)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MemoryGraph.Get() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryGraph_GetRangeStreamingAnd(t *testing.T) {
+	testGetRangeStreamingAnd(t, NewMemoryGraph())
+}
+
+func TestMemoryGraph_GetRange(t *testing.T) {
+	testStoreGetRange(t, NewMemoryGraph())
+}
+
+func TestMemoryGraph_Scan(t *testing.T) {
+	testStoreScan(t, NewMemoryGraph())
+}
+
+func Test_scanWithoutRangeScanner(t *testing.T) {
+	// hide MemoryGraph's Scan, so limits and reversing are done by scan itself
+	testStoreScan(t, struct{ KVStore }{NewMemoryGraph()})
+}
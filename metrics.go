@@ -0,0 +1,100 @@
+package simplegraph
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// OperatorMetrics record what one operator of a query plan actually did, as measured by ExplainAnalyze. Operators
+// only measure themselves when given metrics to fill in, and every method is a no-op on nil metrics.
+type OperatorMetrics struct {
+	Rows int64 `json:"rows"`
+
+	// KeysScanned and BytesRead count the index keys an index scan read from the KVStore
+	KeysScanned int64 `json:"keys_scanned,omitempty"`
+	BytesRead   int64 `json:"bytes_read,omitempty"`
+
+	// PeakBuffered is the most results a sort or join held in memory at once, and PeakBufferedBytes the largest
+	// estimated size of a sort's buffer
+	PeakBuffered      int64 `json:"peak_buffered,omitempty"`
+	PeakBufferedBytes int64 `json:"peak_buffered_bytes,omitempty"`
+
+	// Duration runs from the query starting to the operator's output closing. Operators run concurrently, so it
+	// includes time spent waiting on inputs, and on whatever consumes the output.
+	Duration time.Duration `json:"duration_ns"`
+}
+
+func (metrics *OperatorMetrics) scanned(key []byte) {
+	if metrics == nil {
+		return
+	}
+
+	atomic.AddInt64(&metrics.KeysScanned, 1)
+	atomic.AddInt64(&metrics.BytesRead, int64(len(key)))
+}
+
+func (metrics *OperatorMetrics) buffered(results, bytes int) {
+	if metrics == nil {
+		return
+	}
+
+	raise(&metrics.PeakBuffered, int64(results))
+	raise(&metrics.PeakBufferedBytes, int64(bytes))
+}
+
+// raise sets peak to value if value is larger
+func raise(peak *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(peak)
+
+		if value <= current || atomic.CompareAndSwapInt64(peak, current, value) {
+			return
+		}
+	}
+}
+
+// measure counts the results passing through a stream, and records how long it took to close
+func (metrics *OperatorMetrics) measure(ctx context.Context, input <-chan *SearchResults) <-chan *SearchResults {
+	if metrics == nil {
+		return input
+	}
+
+	output := make(chan *SearchResults)
+	start := time.Now()
+
+	go func(output chan<- *SearchResults) {
+		defer close(output)
+		defer func() {
+			atomic.StoreInt64((*int64)(&metrics.Duration), int64(time.Since(start)))
+		}()
+
+		for result := range input {
+			atomic.AddInt64(&metrics.Rows, 1)
+
+			select {
+			case output <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}(output)
+
+	return output
+}
+
+// snapshot copies the metrics, for reading while operators may still be winding down
+func (metrics *OperatorMetrics) snapshot() *OperatorMetrics {
+	if metrics == nil {
+		return nil
+	}
+
+	return &OperatorMetrics{
+		Rows:              atomic.LoadInt64(&metrics.Rows),
+		KeysScanned:       atomic.LoadInt64(&metrics.KeysScanned),
+		BytesRead:         atomic.LoadInt64(&metrics.BytesRead),
+		PeakBuffered:      atomic.LoadInt64(&metrics.PeakBuffered),
+		PeakBufferedBytes: atomic.LoadInt64(&metrics.PeakBufferedBytes),
+		Duration:          time.Duration(atomic.LoadInt64((*int64)(&metrics.Duration))),
+	}
+}
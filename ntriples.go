@@ -0,0 +1,68 @@
+package simplegraph
+
+import (
+	"bufio"
+	"context"
+	"io"
+)
+
+// NTriplesReader reads edges from RDF N-Triples, one statement at a time
+type NTriplesReader struct {
+	parser *rdfParser
+}
+
+func NewNTriplesReader(r io.Reader) *NTriplesReader {
+	return &NTriplesReader{ parser: newRDFParser(r, false) }
+}
+
+// Read returns the next edge, or io.EOF once the input is exhausted. A syntax error is a *LineError.
+func (nr *NTriplesReader) Read() (Edge, error) {
+	return nr.parser.read()
+}
+
+// NTriplesWriter writes edges as RDF N-Triples. Call Flush once every edge is written.
+type NTriplesWriter struct {
+	w *bufio.Writer
+}
+
+func NewNTriplesWriter(w io.Writer) *NTriplesWriter {
+	return &NTriplesWriter{ w: bufio.NewWriter(w) }
+}
+
+func (nw *NTriplesWriter) Write(edge Edge) error {
+	writeIRI(nw.w, edge.subject)
+	_ = nw.w.WriteByte(' ')
+	writeIRI(nw.w, edge.predicate)
+	_ = nw.w.WriteByte(' ')
+
+	writeObject(nw.w, edge.object, func(iri string) {
+		writeIRI(nw.w, []byte(iri))
+	})
+
+	_, e := nw.w.WriteString(" .\n")
+	return e
+}
+
+func (nw *NTriplesWriter) Flush() error {
+	return nw.w.Flush()
+}
+
+// ImportNTriples adds the edges in an N-Triples document to the graph, in batches like ImportCSV. With DryRun set, it
+// only checks the document.
+func (graph *SimpleGraph) ImportNTriples(ctx context.Context, r io.Reader, options RDFOptions) (*ImportReport, error) {
+	reader := NewNTriplesReader(r)
+
+	return graph.importEdges(ctx, reader.Read, reader.parser.linesRead, options)
+}
+
+// ExportNTriples writes the edges matching the query as N-Triples, and returns how many it wrote
+func (graph *SimpleGraph) ExportNTriples(ctx context.Context, w io.Writer, query Query) (int, error) {
+	writer := NewNTriplesWriter(w)
+	written, e := graph.exportEdges(ctx, query, writer.Write)
+
+	if e != nil {
+		return written, e
+	}
+
+	return written, writer.Flush()
+}
@@ -2,14 +2,17 @@ package simplegraph
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 )
 
+// OrderedStreamJoin is a semi-join of two edge streams sorted by tripleOrder: it emits every edge of stream one whose
+// key also appears in stream two. To join on variables that sit in different positions of each edge, and keep the
+// bindings from both sides, use VariableStreamJoin.
 type OrderedStreamJoin struct {
 	tripleOrder TripleOrder
 }
 
-func (sj *OrderedStreamJoin) join(inputStreamOne, inputStreamTwo <-chan *Edge) <-chan *Edge {
+func (sj *OrderedStreamJoin) join(ctx context.Context, inputStreamOne, inputStreamTwo <-chan *Edge) <-chan *Edge {
 	orderedOutputStream := make(chan *Edge)
 
 	go func(inputStreamOne, inputStreamTwo <-chan *Edge, outputStream chan<- *Edge) {
@@ -23,7 +26,6 @@ func (sj *OrderedStreamJoin) join(inputStreamOne, inputStreamTwo <-chan *Edge) <
 		for {
 			if currentStreamOneEdge == nil {
 				edge, more := <-inputStreamOne
-				fmt.Printf("Match: %v\n", edge)
 
 				if !more {
 					return
@@ -35,7 +37,6 @@ func (sj *OrderedStreamJoin) join(inputStreamOne, inputStreamTwo <-chan *Edge) <
 
 			if currentStreamTwoEdge == nil {
 				edge, more := <-inputStreamTwo
-				fmt.Printf("Candidate: %v\n", edge)
 
 				if !more {
 					return
@@ -48,9 +49,13 @@ func (sj *OrderedStreamJoin) join(inputStreamOne, inputStreamTwo <-chan *Edge) <
 			comparison := bytes.Compare(streamTwoComparisonBytes, streamOneComparisonBytes)
 
 			if comparison == 0 {
-				// key is in both streams, this counts as a match. advance both streams 1
-				outputStream <- currentStreamOneEdge
-				currentStreamTwoEdge = nil
+				// key is in both streams, this counts as a match. only advance stream one, since the next edge in it
+				// may have the same key and needs to match this candidate too
+				select {
+				case outputStream <- currentStreamOneEdge:
+				case <-ctx.Done():
+					return
+				}
 				currentStreamOneEdge = nil
 			} else if comparison == -1 {
 				// candidate is less than key. we need to seek candidate forward until it matches or is greater
@@ -0,0 +1,147 @@
+package simplegraph
+
+import (
+	"context"
+	"encoding/base64"
+)
+
+// Cursor marks where a page of GetEdges or Search results ended. It's opaque, but safe to put in a URL, and is only
+// valid for the query that returned it: pass it to WithCursor on the same query to read the next page.
+type Cursor string
+
+func (cursor Cursor) key() ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(string(cursor))
+}
+
+// WithLimit returns a copy of the query that stops after limit matches. GetEdges and Search only read as much of the
+// index as the limit needs.
+func (query Query) WithLimit(limit int) Query {
+	query.limit = limit
+	return query
+}
+
+// WithOffset returns a copy of the query that skips its first offset matches. The skipped keys are still read, so a
+// cursor is the cheaper way to page through a large result.
+func (query Query) WithOffset(offset int) Query {
+	query.offset = offset
+	return query
+}
+
+// WithCursor returns a copy of the query that resumes after the page that returned the cursor
+func (query Query) WithCursor(cursor Cursor) Query {
+	query.cursor = cursor
+	return query
+}
+
+func (query Query) isPaged() bool {
+	return query.limit != 0 || query.offset != 0 || query.cursor != ""
+}
+
+// page applies a query's offset and limit to a stream read from idx, and remembers the last result it emitted so it
+// can hand out a cursor. A nil page passes everything through.
+type page struct {
+	idx           *hexastoreIndex
+	offset, limit int
+
+	// last and more are written by the page's stage, and read once its output is closed
+	last *Edge
+	more bool
+}
+
+func (query Query) page(idx *hexastoreIndex) *page {
+	if !query.isPaged() {
+		return nil
+	}
+
+	return &page{ idx: idx, offset: query.offset, limit: query.limit }
+}
+
+// scanLimit is the number of keys the page needs read: one past its end, to tell whether there's another page
+func (page *page) scanLimit() int {
+	if page == nil || page.limit == 0 {
+		return 0
+	}
+
+	return page.offset + page.limit + 1
+}
+
+func (page *page) edges(ctx context.Context, input <-chan *Edge) <-chan *Edge {
+	if page == nil {
+		return input
+	}
+
+	output := make(chan *Edge)
+
+	go func(output chan<- *Edge) {
+		defer close(output)
+
+		skipped, sent := 0, 0
+		for edge := range input {
+			if skipped < page.offset {
+				skipped++
+				continue
+			}
+
+			if page.limit > 0 && sent == page.limit {
+				page.more = true
+				return
+			}
+
+			select {
+			case output <- edge:
+			case <-ctx.Done():
+				return
+			}
+
+			sent++
+			page.last = edge
+		}
+	}(output)
+
+	return output
+}
+
+func (page *page) results(ctx context.Context, input <-chan *SearchResults) <-chan *SearchResults {
+	if page == nil {
+		return input
+	}
+
+	output := make(chan *SearchResults)
+
+	go func(output chan<- *SearchResults) {
+		defer close(output)
+
+		skipped, sent := 0, 0
+		for result := range input {
+			if skipped < page.offset {
+				skipped++
+				continue
+			}
+
+			if page.limit > 0 && sent == page.limit {
+				page.more = true
+				return
+			}
+
+			select {
+			case output <- result:
+			case <-ctx.Done():
+				return
+			}
+
+			sent++
+			page.last = result.edges[0]
+		}
+	}(output)
+
+	return output
+}
+
+// cursor resumes after the last result of the page, or is empty if there's nothing after it
+func (page *page) cursor() Cursor {
+	if page == nil || !page.more || page.last == nil {
+		return ""
+	}
+
+	return Cursor(base64.RawURLEncoding.EncodeToString(page.idx.toBytes(page.last)))
+}
@@ -0,0 +1,233 @@
+package simplegraph
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// scanRecordingStore remembers the options of every range scan it serves
+type scanRecordingStore struct {
+	*MemoryGraph
+
+	mu    sync.Mutex
+	scans []ScanOptions
+}
+
+func (s *scanRecordingStore) Scan(ctx context.Context, begin, end []byte, options ScanOptions, stream chan<- []byte) error {
+	s.mu.Lock()
+	s.scans = append(s.scans, options)
+	s.mu.Unlock()
+
+	return s.MemoryGraph.Scan(ctx, begin, end, options, stream)
+}
+
+func collectEdgePage(t *testing.T, graph *SimpleGraph, query Query) ([]string, Cursor) {
+	stream, e := graph.GetEdges(context.Background(), query)
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var subjects []string
+	for edge := range stream.Edges() {
+		subjects = append(subjects, string(edge.Subject()))
+	}
+
+	if e := stream.Err(); e != nil {
+		t.Fatal(e)
+	}
+
+	return subjects, stream.Cursor()
+}
+
+func TestSimpleGraph_GetEdgesPaging(t *testing.T) {
+	graph := seasonsGraph()
+
+	// read from pos, so in team order
+	team := NewQuery().WithPredicate([]byte("team"))
+
+	tests := []struct {
+		name       string
+		query      Query
+		want       []string
+		wantCursor bool
+	}{
+		{
+			"it limits",
+			team.WithLimit(2),
+			[]string{"kevin mchale", "larry bird"},
+			true,
+		},
+		{
+			"it skips the offset",
+			team.WithOffset(1).WithLimit(2),
+			[]string{"larry bird", "bill walton"},
+			true,
+		},
+		{
+			"it has no cursor when the limit reaches the end",
+			team.WithOffset(2).WithLimit(2),
+			[]string{"bill walton", "kevin garnett"},
+			false,
+		},
+		{
+			"it returns everything after the offset without a limit",
+			team.WithOffset(3),
+			[]string{"kevin garnett"},
+			false,
+		},
+		{
+			"it limits ranges",
+			NewQuery().WithPredicate([]byte("seasons played")).WithObjectRange(AtLeast(int64(0))).WithLimit(2),
+			[]string{"len bias", "bill walton"},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cursor := collectEdgePage(t, graph, tt.query)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			if (cursor != "") != tt.wantCursor {
+				t.Errorf("got cursor %q, want one: %v", cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestSimpleGraph_GetEdgesCursor(t *testing.T) {
+	graph := seasonsGraph()
+	query := NewQuery().WithPredicate([]byte("seasons played")).WithObjectRange(AtLeast(int64(0))).WithLimit(2)
+
+	var pages [][]string
+	var cursor Cursor
+
+	for {
+		subjects, next := collectEdgePage(t, graph, query.WithCursor(cursor))
+		pages = append(pages, subjects)
+
+		if next == "" {
+			break
+		}
+
+		cursor = next
+	}
+
+	want := [][]string{
+		{"len bias", "bill walton"},
+		{"kevin mchale", "larry bird"},
+		{"kevin garnett"},
+	}
+
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("got %v, want %v", pages, want)
+	}
+
+	// the cursor points into the range of the query that returned it
+	for _, other := range []Query{
+		NewQuery().WithPredicate([]byte("team")),
+		NewQuery().WithPredicate([]byte("seasons played")).WithObjectRange(LessThan(int64(0))),
+		NewQuery().WithSubject([]byte("larry bird")),
+	} {
+		if _, e := graph.GetEdges(context.Background(), other.WithCursor(cursor)); e == nil {
+			t.Errorf("%v accepted a cursor from %v", other, query)
+		}
+	}
+
+	if _, e := graph.GetEdges(context.Background(), query.WithCursor("not base64!")); e == nil {
+		t.Errorf("a malformed cursor was accepted")
+	}
+}
+
+func TestSimpleGraph_PagingValidation(t *testing.T) {
+	graph := seasonsGraph()
+	ctx := context.Background()
+	query := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("team"))
+
+	if _, e := graph.GetEdges(ctx, query.WithLimit(-1)); e == nil {
+		t.Errorf("a negative limit was accepted")
+	}
+
+	if _, e := graph.Search(ctx, query.WithOffset(-1)); e == nil {
+		t.Errorf("a negative offset was accepted")
+	}
+
+	_, e := graph.Match(ctx, query.WithLimit(1), NewQuery().WithSubjectVariable("x").WithPredicateVariable("p"))
+	if e == nil || !strings.Contains(e.Error(), "not to Match") {
+		t.Errorf("Match accepted a limited pattern: %v", e)
+	}
+}
+
+func TestSimpleGraph_LimitPushdown(t *testing.T) {
+	store := &scanRecordingStore{ MemoryGraph: NewMemoryGraph() }
+	graph := NewSimpleGraph(store)
+	ctx := context.Background()
+
+	var edges []Edge
+	for _, subject := range []string{"a", "b", "c", "d", "e", "f"} {
+		edges = append(edges, NewEdge([]byte(subject), []byte("knows"), []byte(subject)))
+		edges = append(edges, NewEdge([]byte(subject), []byte("knows"), []byte("z")))
+	}
+
+	if e := graph.AddEdges(edges); e != nil {
+		t.Fatal(e)
+	}
+
+	_, _ = collectEdgePage(t, graph, NewQuery().WithPredicate([]byte("knows")).WithOffset(1).WithLimit(2))
+
+	if !reflect.DeepEqual(store.scans, []ScanOptions{{ Limit: 4 }}) {
+		t.Errorf("got scans %v, want one limited to the offset, limit and one more", store.scans)
+	}
+
+	// every other edge binds ?x twice, so the scan can't be limited, but the results still are
+	store.scans = nil
+	query := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("knows")).WithObjectVariable("x").WithLimit(2)
+
+	var got []string
+	var cursor Cursor
+
+	for {
+		stream, e := graph.Search(ctx, query.WithCursor(cursor))
+
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		var page []string
+		for result := range stream.Results() {
+			value, _ := result.Get("x")
+			page = append(page, string(value.([]byte)))
+		}
+
+		if e := stream.Err(); e != nil {
+			t.Fatal(e)
+		}
+
+		if len(page) > 2 {
+			t.Fatalf("got a page of %v", page)
+		}
+
+		got = append(got, page...)
+
+		if cursor = stream.Cursor(); cursor == "" {
+			break
+		}
+	}
+
+	if want := []string{"a", "b", "c", "d", "e", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, scan := range store.scans {
+		if scan.Limit != 0 {
+			t.Errorf("a search with a repeated variable was limited to %v keys", scan.Limit)
+		}
+	}
+}
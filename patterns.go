@@ -0,0 +1,218 @@
+package simplegraph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParsePatterns reads triple patterns for Match from text like
+//
+//	?x "played for" Celtics . ?x "played for" Timberwolves
+//
+// Each pattern is a subject, predicate and object separated by spaces, and patterns are separated by full stops. A
+// term is a ?variable, _ to match anything, or a value: a "quoted string" with Go escapes, or a bare word, which are
+// both matched as []byte. Objects of the other types are written int(13), float(1.5), bool(true), string("text") or
+// time(2006-01-02T15:04:05Z).
+func ParsePatterns(text string) ([]Query, error) {
+	tokens, e := patternTokens(text)
+
+	if e != nil {
+		return nil, e
+	}
+
+	var patterns []Query
+	var terms []string
+
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i] != "." {
+			terms = append(terms, tokens[i])
+			continue
+		}
+
+		// a trailing full stop is allowed
+		if len(terms) == 0 && i == len(tokens) && len(patterns) > 0 {
+			break
+		}
+
+		if len(terms) != 3 {
+			return nil, fmt.Errorf("pattern %v has %v terms, expected a subject, predicate and object: %v",
+				len(patterns) + 1, len(terms), strings.Join(terms, " "))
+		}
+
+		pattern, e := patternFromTerms(terms)
+
+		if e != nil {
+			return nil, fmt.Errorf("pattern %v: %v", len(patterns) + 1, e)
+		}
+
+		patterns = append(patterns, pattern)
+		terms = nil
+	}
+
+	return patterns, nil
+}
+
+func patternFromTerms(terms []string) (Query, error) {
+	query := NewQuery()
+
+	for i, term := range terms {
+		if term == "_" {
+			continue
+		}
+
+		if strings.HasPrefix(term, "?") {
+			name := term[1:]
+
+			if name == "" {
+				return Query{}, fmt.Errorf("%v is missing its name", term)
+			}
+
+			switch i {
+			case 0:
+				query = query.WithSubjectVariable(name)
+			case 1:
+				query = query.WithPredicateVariable(name)
+			case 2:
+				query = query.WithObjectVariable(name)
+			}
+
+			continue
+		}
+
+		value, e := patternValue(term)
+
+		if e != nil {
+			return Query{}, e
+		}
+
+		field, isBytes := value.([]byte)
+
+		switch {
+		case i == 2:
+			query = query.WithObject(value)
+		case !isBytes:
+			return Query{}, fmt.Errorf("only objects can be typed, not %v", term)
+		case i == 0:
+			query = query.WithSubject(field)
+		case i == 1:
+			query = query.WithPredicate(field)
+		}
+	}
+
+	return query, nil
+}
+
+func patternValue(term string) (interface{}, error) {
+	if strings.HasPrefix(term, `"`) {
+		text, e := strconv.Unquote(term)
+
+		if e != nil {
+			return nil, fmt.Errorf("invalid quoted string %v", term)
+		}
+
+		return []byte(text), nil
+	}
+
+	open := strings.Index(term, "(")
+	if open < 0 || !strings.HasSuffix(term, ")") {
+		return []byte(term), nil
+	}
+
+	kind, argument := term[:open], term[open + 1:len(term) - 1]
+	var value interface{}
+	var e error
+
+	switch kind {
+	case "int":
+		value, e = strconv.ParseInt(argument, 10, 64)
+	case "float":
+		value, e = strconv.ParseFloat(argument, 64)
+	case "bool":
+		value, e = strconv.ParseBool(argument)
+	case "string":
+		value, e = strconv.Unquote(argument)
+	case "time":
+		var t time.Time
+		t, e = time.Parse(time.RFC3339Nano, argument)
+		value = t.UTC()
+	default:
+		// just a word with brackets in it
+		return []byte(term), nil
+	}
+
+	if e != nil {
+		return nil, fmt.Errorf("invalid %v", term)
+	}
+
+	return value, nil
+}
+
+// patternTokens splits text into terms and full stops. Quoted strings, and the brackets of typed values, can hold
+// spaces.
+func patternTokens(text string) ([]string, error) {
+	var tokens []string
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		start, depth, quoted := i, 0, false
+
+		for ; i < len(runes); i++ {
+			r := runes[i]
+
+			if quoted {
+				if r == '\\' {
+					i++
+				} else if r == '"' {
+					quoted = false
+				}
+
+				continue
+			}
+
+			if r == '"' {
+				quoted = true
+			} else if r == '(' {
+				depth++
+			} else if r == ')' && depth > 0 {
+				depth--
+			} else if unicode.IsSpace(r) && depth == 0 {
+				break
+			}
+		}
+
+		if quoted {
+			return nil, fmt.Errorf("unterminated quote in %v", string(runes[start:]))
+		}
+
+		token := string(runes[start:i])
+
+		// a full stop can end the last term, as in ?x friend paul.
+		if len(token) > 1 && strings.HasSuffix(token, ".") && (strings.HasPrefix(token, `"`) || !strings.Contains(token[:len(token) - 1], ".")) {
+			tokens = append(tokens, token[:len(token) - 1], ".")
+			continue
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// Variables lists the names of the patterns' variables, in the order they first appear
+func Variables(patterns ...Query) []string {
+	var names []string
+
+	for _, pattern := range patterns {
+		names = appendVariables(names, pattern)
+	}
+
+	return names
+}
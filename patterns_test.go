@@ -0,0 +1,84 @@
+package simplegraph
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []Query
+		wantErr bool
+	}{
+		{
+			name: "two patterns",
+			text: `?x "played for" Celtics . ?x "played for" Timberwolves`,
+			want: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("played for")).WithObject([]byte("Celtics")),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("played for")).WithObject([]byte("Timberwolves")),
+			},
+		},
+		{
+			name: "trailing full stops and wildcards",
+			text: "_ ?p paul. ?x friend \"a \\\"quoted\\\" name\".",
+			want: []Query{
+				NewQuery().WithPredicateVariable("p").WithObject([]byte("paul")),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("friend")).WithObject([]byte(`a "quoted" name`)),
+			},
+		},
+		{
+			name: "typed objects",
+			text: `?x age int(13) . ?x height float(1.5) . ?x active bool(true) . ?x note string("a b") . ?x born time(2001-02-03T04:05:06Z)`,
+			want: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("age")).WithObject(int64(13)),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("height")).WithObject(1.5),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("active")).WithObject(true),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("note")).WithObject("a b"),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("born")).WithObject(time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)),
+			},
+		},
+		{
+			name: "words with brackets and full stops",
+			text: "http://example.org/a f(x) 1.5",
+			want: []Query{
+				NewQuery().WithSubject([]byte("http://example.org/a")).WithPredicate([]byte("f(x)")).WithObject([]byte("1.5")),
+			},
+		},
+		{ name: "too few terms", text: "?x friend", wantErr: true },
+		{ name: "empty pattern", text: "?x friend paul . .", wantErr: true },
+		{ name: "nothing", text: "  ", wantErr: true },
+		{ name: "unterminated quote", text: `?x friend "paul`, wantErr: true },
+		{ name: "typed subject", text: "int(1) friend paul", wantErr: true },
+		{ name: "invalid typed object", text: "?x age int(old)", wantErr: true },
+		{ name: "unnamed variable", text: "? friend paul", wantErr: true },
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, e := ParsePatterns(tt.text)
+
+			if (e != nil) != tt.wantErr {
+				t.Fatalf("ParsePatterns() error = %v, wantErr %v", e, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePatterns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	patterns, e := ParsePatterns("?x friend ?y . ?y ?p ?x . ?z friend ?y")
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if got := Variables(patterns...); !reflect.DeepEqual(got, []string{ "x", "y", "p", "z" }) {
+		t.Errorf("got %v", got)
+	}
+}
@@ -1,6 +1,8 @@
 package simplegraph
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 )
@@ -38,8 +40,8 @@ If 3 is POS could emit tuples "_ | _| y"
 
  */
 
-func transformQuery(query Query) map[DataField][]byte {
-	dataFieldsInQuery := make(map[DataField][]byte)
+func transformQuery(query Query) map[DataField]interface{} {
+	dataFieldsInQuery := make(map[DataField]interface{})
 
 	if query.subject != nil {
 		dataFieldsInQuery[SUBJECT] = query.subject
@@ -56,11 +58,12 @@ func transformQuery(query Query) map[DataField][]byte {
 	return dataFieldsInQuery
 }
 
-func findIndices(query map[DataField][]byte) (indices []*hexastoreIndex, matchDepth int) {
+func findIndices(query map[DataField]interface{}) (indices []*hexastoreIndex, matchDepth int) {
 	maxDepthFound := 0
 	var candidateIndices []*hexastoreIndex
 
-	for _, index := range Indices {
+	for _, name := range indexNames() {
+		index := Indices[name]
 		matchDepth := index.matchDepth(query)
 
 		if matchDepth > maxDepthFound {
@@ -83,206 +86,442 @@ type queryPlan struct {
 	source tripleSource
 }
 
+// tripleSource is a node in a query plan. Every node streams variable bindings sorted by its TripleOrder.
 type tripleSource interface {
 	getTripleOrder() *TripleOrder
+	getEstimate() *estimate
+	explain() *PlanNode
+	execute(ctx context.Context, graph *SimpleGraph, errs *pipelineError) <-chan *SearchResults
 }
 
 type indexScanSource struct {
 	query *Query
 	idx *hexastoreIndex
 	tripleOrder *TripleOrder
+	estimate *estimate
+	metrics *OperatorMetrics
+}
+
+func (model *costModel) newIndexScanSource(query Query, preferredOrder []string) *indexScanSource {
+	idx, tripleOrder := chooseIndex(query, preferredOrder)
+
+	return &indexScanSource{
+		query:       &query,
+		idx:         idx,
+		tripleOrder: tripleOrder,
+		estimate:    model.scanEstimate(query),
+		metrics:     model.newMetrics(),
+	}
 }
 
 func (iss *indexScanSource) getTripleOrder() *TripleOrder {
 	return iss.tripleOrder
 }
 
+func (iss *indexScanSource) getEstimate() *estimate {
+	return iss.estimate
+}
+
+func (iss *indexScanSource) execute(ctx context.Context, graph *SimpleGraph, errs *pipelineError) <-chan *SearchResults {
+	stream := VariableStream{
+		variables: iss.query.toVariableMap(),
+	}
+
+	span, e := iss.idx.spanFor(*iss.query)
+
+	if e != nil {
+		errs.set(e)
+	}
+
+	return iss.metrics.measure(ctx, stream.join(ctx, graph._getRangeStreaming(ctx, iss.idx, span, errs, iss.metrics)))
+}
+
 type bufferSortedSource struct {
 	tripleSource
 	tripleOrder *TripleOrder
+	metrics *OperatorMetrics
 }
 
 func (bss *bufferSortedSource) getTripleOrder() *TripleOrder {
 	return bss.tripleOrder
 }
 
+func (bss *bufferSortedSource) getEstimate() *estimate {
+	return bss.tripleSource.getEstimate()
+}
+
+func (bss *bufferSortedSource) execute(ctx context.Context, graph *SimpleGraph, errs *pipelineError) <-chan *SearchResults {
+	sort := SortStream{
+		tripleOrder:  *bss.tripleOrder,
+		memoryBudget: graph.sortMemoryBudget,
+		metrics:      bss.metrics,
+	}
+
+	return bss.metrics.measure(ctx, sort.join(ctx, bss.tripleSource.execute(ctx, graph, errs), errs))
+}
+
+// mergeJoin joins its sources, left to right, on the variables in joinOrder. Every source must be sorted with
+// joinOrder as a prefix of its own ordering. The output keeps the ordering of the first source.
 type mergeJoin struct {
 	joins []tripleSource
+	joinOrder *TripleOrder
 	tripleOrder *TripleOrder
+	estimate *estimate
+	metrics *OperatorMetrics
 }
 
 func (lmj *mergeJoin) getTripleOrder() *TripleOrder {
 	return lmj.tripleOrder
 }
 
-// ("paul", a, b) | ("paul", b, a) -- want spo x sop. AB ordering output, S fixed, PO order. next stage choose object, predicate to get same AB ordering
-// ("paul", _, _)
+func (lmj *mergeJoin) getEstimate() *estimate {
+	return lmj.estimate
+}
+
+func (lmj *mergeJoin) execute(ctx context.Context, graph *SimpleGraph, errs *pipelineError) <-chan *SearchResults {
+	stream := lmj.joins[0].execute(ctx, graph, errs)
+
+	join := VariableStreamJoin{
+		variables: lmj.joinOrder.variableOrder,
+		metrics:   lmj.metrics,
+	}
 
-func generateQueryPlan(queries ... Query) *queryPlan {
+	for _, source := range lmj.joins[1:] {
+		stream = join.join(ctx, stream, source.execute(ctx, graph, errs))
+	}
 
+	return lmj.metrics.measure(ctx, stream)
+}
 
-	OUTER:
-	for i := 0; i < len(queries) - 1; i++ {
-		thisQuery := queries[i]
-		nextQuery := queries[i+1]
+// hashJoin joins its sources, left to right, on the variables in joinOrder. Its sources can be in any order, and so
+// is its output.
+type hashJoin struct {
+	joins []tripleSource
+	joinOrder *TripleOrder
+	tripleOrder *TripleOrder
+	estimate *estimate
+	metrics *OperatorMetrics
+}
 
-		possibleIndicesThisQuery, matchDepthThisQuery := findIndices(transformQuery(thisQuery))
-		possibleIndicesNextQuery, matchDepthNextQuery := findIndices(transformQuery(nextQuery))
+func (hj *hashJoin) getTripleOrder() *TripleOrder {
+	return hj.tripleOrder
+}
 
-		variableMapThisQuery := thisQuery.toVariableMap()
-		variableMapNextQuery := nextQuery.toVariableMap()
+func (hj *hashJoin) getEstimate() *estimate {
+	return hj.estimate
+}
 
-		var commonVariables []string
+func (hj *hashJoin) execute(ctx context.Context, graph *SimpleGraph, errs *pipelineError) <-chan *SearchResults {
+	stream := hj.joins[0].execute(ctx, graph, errs)
 
-		for _, v1 := range variableMapThisQuery {
-			for _, v2 := range variableMapNextQuery {
-				if v1 == v2 {
-					commonVariables = append(commonVariables, v1)
-				}
-			}
-		}
+	join := VariableHashJoin{
+		variables: hj.joinOrder.variableOrder,
+		metrics:   hj.metrics,
+	}
 
-		for _, idxThisQuery := range possibleIndicesThisQuery {
-			var variableOrderingThisQuery []string
-			var variableOrderingNextQuery []string
+	for _, source := range hj.joins[1:] {
+		stream = join.join(ctx, stream, source.execute(ctx, graph, errs))
+	}
 
-			for _, indexOrderedDataField := range idxThisQuery.ordering {
-				v1 := variableMapThisQuery[indexOrderedDataField]
+	return hj.metrics.measure(ctx, stream)
+}
 
-				if contains(v1, commonVariables) {
-					variableOrderingThisQuery = append(variableMapThisQuery, v1)
-				}
-			}
+// chooseIndex picks the index to scan a pattern with. The pattern's fixed fields have to lead the index so they form
+// the key prefix, followed by the object if the pattern has an object range. Of the indices that allow that, the one
+// whose remaining fields sort the pattern's variables closest to preferredOrder wins, so the scan can feed a merge
+// join without being re-sorted.
+func chooseIndex(query Query, preferredOrder []string) (*hexastoreIndex, *TripleOrder) {
+	fixed := transformQuery(query)
+	variables := query.toVariableMap()
 
-			hasOrderingMatch := false
-			for _, idxNextQuery := range possibleIndicesNextQuery {
-				for _, indexOrderedDataField := range idxNextQuery.ordering {
-					v2 := variableMapNextQuery[indexOrderedDataField]
+	var chosen *hexastoreIndex
+	var chosenOrder *TripleOrder
+	bestScore := -1
 
-					if contains(v2, commonVariables) {
-						variableOrderingNextQuery = append(variableOrderingNextQuery, v2)
-					}
-				}
+	for _, name := range indexNames() {
+		idx := Indices[name]
 
-				// now we have orderings of common variables + possible indices.
-				// if the variable orderings are the same
+		if idx.matchDepth(fixed) != len(fixed) {
+			continue
+		}
 
+		// a range has to come straight after the prefix to narrow the scan
+		if query.objectRange != nil && idx.ordering[len(fixed)] != OBJECT {
+			continue
+		}
 
-				for i := range variableOrderingThisQuery {
-					if i > len(variableOrderingNextQuery) {
-						continue
-					}
+		tripleOrder := &TripleOrder{ dataFieldOrder: idx.ordering[len(fixed):] }
 
-					if variableOrderingThisQuery[i] != variableOrderingNextQuery[i] {
+		for _, dataField := range tripleOrder.dataFieldOrder {
+			variable, ok := variables[dataField]
 
-					}
-				}
+			// a field that's neither fixed nor bound isn't sorted on in any useful way, nor is anything after it
+			if !ok {
+				break
+			}
 
-				continue OUTER
+			if !contains(variable, tripleOrder.variableOrder) {
+				tripleOrder.variableOrder = append(tripleOrder.variableOrder, variable)
 			}
 		}
-	}
-	
-	
 
-}
+		score := 0
+		for score < len(preferredOrder) && score < len(tripleOrder.variableOrder) &&
+			preferredOrder[score] == tripleOrder.variableOrder[score] {
+			score++
+		}
 
-func contains(variable string, commonVariables []string) bool {
-	for _, cv := range commonVariables {
-		if cv == variable {
-			return true
+		if score > bestScore {
+			chosen, chosenOrder, bestScore = idx, tripleOrder, score
 		}
 	}
 
-	return false
+	return chosen, chosenOrder
 }
 
-func findIndexPairV2(query1, query2 map[DataField][]byte) (idx1 *hexastoreIndex, idx2 *hexastoreIndex) {
-	indices1, depth1 := findIndices(query1)
-	indices2, depth2 := findIndices(query2)
+// indexNames lists the names of Indices in a fixed order, so planning doesn't depend on map iteration order
+func indexNames() []string {
+	return []string{ "spo", "sop", "pos", "pso", "osp", "ops" }
+}
 
-	matchDepth1 := indices1[0].matchDepth(query1)
-	matchDepth2 := indices2[0].matchDepth(query2)
+/*
+generateQueryPlan builds a left-deep plan of joins over the patterns, preferring merge joins that keep the ordering
+of the index scans.
 
-	source := &indexScanSource{}
+use depth to find constrained fields and unconstrained fields (variables)
 
-	plan := queryPlan{
-		source: &mergeJoin{
-			joins: []tripleSource{ &bufferSortedSource{source: source}, source, source  },
-		},
-	}
+of the constrained fields, we know these are our fixed range prefixes
+	if two constrained fields: index we choose doesn't matter
 
-	fmt.Printf("%v", plan)
+if one unconstrained field:
+	order s.t. the VARIABLE ORDERING is the same in the next stream
 
-	/*
-	use depth to find constrained fields and unconstrained fields (variables)
+e.g. in (paul, friend, x) piped to (x, friend, y)
+	for first stage: we know S and P are fixed. can use either SPO or PSO
+	this yields results in X ordering
 
-	of the constrained fields, we know these are our fixed range prefixes
-		if two constrained fields: index we choose doesn't matter
+	for the second stage, we know P is fixed, so now we choose between PSO and POS
+	but we know the input is in X ordering, so we want to match that.
+	here, X is the Subject. So therefore we choose PSO
 
-	if one unconstrained field:
-		order s.t. the VARIABLE ORDERING is the same in the next stream
+	then say (x, friend, y) | (y, friend, jess)
 
-	e.g. in (paul, friend, x) piped to (x, friend, y)
-		for first stage: we know S and P are fixed. can use either SPO or PSO
-		this yields results in X ordering
+	we know P and O are fixed, so we have POS and OPS to choose from.
+	we know that the input is in XY ordering, which is SO
 
-		for the second stage, we know P is fixed, so now we choose between PSO and POS
-		but we know the input is in X ordering, so we want to match that.
-		here, X is the Subject. So therefore we choose PSO
+	we don't have an available ordering for this, so we must collect the results into memory
+	and sort them to Y ordering to match plan for next constraint
 
-		then say (x, friend, y) | (y, friend, jess)
 
-		we know P and O are fixed, so we have POS and OPS to choose from.
-		we know that the input is in XY ordering, which is SO
+	output:
+		stage 1: SPO | PSO, with object x variable
+		stage 2: PSO, with object x y variables, sort stream by Y (OS or OP depending on next index)
+		stage 3: POS | OPS, with variable y
 
-		we don't have an available ordering for this, so we must collect the results into memory
-		and sort them to Y ordering to match plan for next constraint
+	when a join can't keep the ordering, a hash join on the smaller side avoids buffering and sorting the other side,
+	as long as the smaller side fits in memory. its output is unordered, so later stages have to do the same.
 
+	the model's estimates decide the order the patterns are joined in, and between hashing and sorting.
+*/
+func (model *costModel) generateQueryPlan(queries ... Query) (*queryPlan, error) {
+	if len(queries) == 0 {
+		return nil, errors.New("a query needs at least one pattern")
+	}
 
-		output:
-			stage 1: SPO | PSO, with object x variable
-			stage 2: PSO, with object x y variables, sort stream by Y (OS or OP depending on next index)
-			stage 3: POS | OPS, with variable y
-	 */
+	for _, query := range queries {
+		if e := query.validate(); e != nil {
+			return nil, e
+		}
 
+		if query.isPaged() {
+			return nil, errors.New("limits, offsets and cursors apply to GetEdges and Search, not to Match patterns")
+		}
+	}
 
+	queries = model.orderPatterns(queries)
 
-	dataField1 := indices1[0].ordering[0]
-	dataField2 := indices2[0].ordering[0]
+	var preferredOrder []string
+	if len(queries) > 1 {
+		preferredOrder = sharedVariables(variableNames(queries[0]), queries[1])
+	}
 
-	// can answer both by scanning one keyrange
-	// can join these streams together using the most selective one.
-	// separate db streams from logic joinsd
-	if dataField1 == dataField2 {
-		if matchDepth1 >= matchDepth2 {
-			// this could also be randomized since we want to spread load across both indices potentially
-			return indices1[0], indices1[0]
-		} else {
-			return indices2[1], indices2[1]
+	var source tripleSource = model.newIndexScanSource(queries[0], preferredOrder)
+	bound := variableNames(queries[0])
+
+	for _, query := range queries[1:] {
+		shared := sharedVariables(bound, query)
+
+		// join on as much of the current ordering as the next pattern shares
+		var joinOrder []string
+		for _, variable := range source.getTripleOrder().variableOrder {
+			if !contains(variable, shared) {
+				break
+			}
+
+			joinOrder = append(joinOrder, variable)
 		}
-	} else {
-		// we want the same sorting order between the two, in so much as is possible
-		// easiest way to think of this is as a palindrome: if we choose `spo` for 1, we want `pos` for 2
-		// since `po` will be in sorted order relative to s in each case, and we'll only be scanning over one `s`
 
-		for _, idx1Candidate := range indices1 {
-			for _, idx2Candidate := range indices2 {
-				if idx1Candidate.ordering[1] == idx2Candidate.ordering[0] &&
-					idx1Candidate.ordering[2] == idx2Candidate.ordering[1] {
-					return idx1Candidate, idx2Candidate
+		if len(joinOrder) == 0 && len(shared) > 0 {
+			next := model.newIndexScanSource(query, shared)
+			left, right := source.getEstimate(), next.getEstimate()
+			rightSorted := hasPrefix(next.getTripleOrder().variableOrder, shared)
+
+			// the current ordering is no use for a merge join. hashing the smaller side is cheaper than buffering and
+			// re-sorting the current results, unless it won't fit in memory
+			if model.hashFits(left, right) && hashJoinCost(left, right) <= sortMergeJoinCost(left, right, rightSorted) {
+				source = &hashJoin{
+					joins:       []tripleSource{ source, next },
+					joinOrder:   &TripleOrder{ variableOrder: shared },
+					tripleOrder: &TripleOrder{},
+					estimate:    joinEstimate(left, right, shared),
+					metrics:     model.newMetrics(),
 				}
+
+				bound = appendVariables(bound, query)
+				continue
+			}
+
+			joinOrder = shared
+			source = &bufferSortedSource{
+				tripleSource: source,
+				tripleOrder:  &TripleOrder{ variableOrder: shared },
+				metrics:      model.newMetrics(),
 			}
 		}
+
+		var next tripleSource = model.newIndexScanSource(query, joinOrder)
+
+		if !hasPrefix(next.getTripleOrder().variableOrder, joinOrder) {
+			next = &bufferSortedSource{
+				tripleSource: next,
+				tripleOrder:  &TripleOrder{ variableOrder: joinOrder },
+				metrics:      model.newMetrics(),
+			}
+		}
+
+		source = &mergeJoin{
+			joins:       []tripleSource{ source, next },
+			joinOrder:   &TripleOrder{ variableOrder: joinOrder },
+			tripleOrder: source.getTripleOrder(),
+			estimate:    joinEstimate(source.getEstimate(), next.getEstimate(), shared),
+			metrics:     model.newMetrics(),
+		}
+
+		bound = appendVariables(bound, query)
 	}
 
-	panic(fmt.Sprintf("couldnt find indices for %v, %v", query1, query2))
+	return &queryPlan{ source: source }, nil
+}
+
+// orderPatterns starts with the pattern estimated to match the fewest edges, then repeatedly joins the smallest
+// pattern that shares a variable with those before it, so intermediate results stay small and joins are on shared
+// variables wherever the query allows it. Ties keep the order given.
+func (model *costModel) orderPatterns(queries []Query) []Query {
+	rows := make([]float64, len(queries))
+	for i, query := range queries {
+		rows[i] = model.scanEstimate(query).rows
+	}
+
+	first := 0
+	for i := range queries {
+		if rows[i] < rows[first] {
+			first = i
+		}
+	}
+
+	remaining := append([]Query(nil), queries[:first]...)
+	remaining = append(remaining, queries[first+1:]...)
+	remainingRows := append([]float64(nil), rows[:first]...)
+	remainingRows = append(remainingRows, rows[first+1:]...)
+
+	ordered := []Query{ queries[first] }
+	bound := variableNames(queries[first])
+
+	for len(remaining) > 0 {
+		next, connected := 0, false
+
+		for i, query := range remaining {
+			shares := len(sharedVariables(bound, query)) > 0
+
+			if (shares && !connected) || (shares == connected && remainingRows[i] < remainingRows[next]) {
+				next, connected = i, shares
+			}
+		}
+
+		ordered = append(ordered, remaining[next])
+		bound = append(bound, variableNames(remaining[next])...)
+		remaining = append(remaining[:next], remaining[next+1:]...)
+		remainingRows = append(remainingRows[:next], remainingRows[next+1:]...)
+	}
+
+	return ordered
+}
+
+// appendVariables adds the pattern's variables to bound, skipping any already there
+func appendVariables(bound []string, query Query) []string {
+	for _, variable := range variableNames(query) {
+		if !contains(variable, bound) {
+			bound = append(bound, variable)
+		}
+	}
+
+	return bound
+}
+
+// variableNames lists the distinct variables of a pattern in subject, predicate, object order
+func variableNames(query Query) []string {
+	var names []string
+
+	for _, name := range []string{ query.subjectVariable, query.predicateVariable, query.objectVariable } {
+		if name != "" && !contains(name, names) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// sharedVariables lists the variables in bound that the pattern also uses, in the order of bound
+func sharedVariables(bound []string, query Query) []string {
+	var shared []string
+	names := variableNames(query)
+
+	for _, name := range bound {
+		if contains(name, names) && !contains(name, shared) {
+			shared = append(shared, name)
+		}
+	}
+
+	return shared
+}
+
+func hasPrefix(variableOrder, prefix []string) bool {
+	if len(prefix) > len(variableOrder) {
+		return false
+	}
+
+	for i := range prefix {
+		if variableOrder[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
 }
 
+func contains(variable string, commonVariables []string) bool {
+	for _, cv := range commonVariables {
+		if cv == variable {
+			return true
+		}
+	}
+
+	return false
+}
 
-func findIndexPair(query1, query2 map[DataField][]byte) (idx1 *hexastoreIndex, idx2 *hexastoreIndex) {
-	indices1 := findIndices(query1)
-	indices2 := findIndices(query2)
+func findIndexPair(query1, query2 map[DataField]interface{}) (idx1 *hexastoreIndex, idx2 *hexastoreIndex) {
+	indices1, _ := findIndices(query1)
+	indices2, _ := findIndices(query2)
 
 	matchDepth1 := indices1[0].matchDepth(query1)
 	matchDepth2 := indices2[0].matchDepth(query2)
@@ -320,16 +559,83 @@ func findIndexPair(query1, query2 map[DataField][]byte) (idx1 *hexastoreIndex, i
 
 type VariableResult struct {
 	name string
-	value []byte
+	value interface{}
 }
 
+func (vr *VariableResult) Name() string {
+	return vr.name
+}
+
+// Value returns the bound value: a []byte for subjects and predicates, and whatever type the object was added with
+// for objects
+func (vr *VariableResult) Value() interface{} {
+	return vr.value
+}
+
+// SearchResults is one solution to a search: the edges matched by each pattern, and the value bound to each variable
 type SearchResults struct {
-	edge      *Edge
-	variables map[DataField]*VariableResult
+	edges     []*Edge
+	variables map[string]*VariableResult
+}
+
+// Edge returns the edge the first pattern matched
+func (sr *SearchResults) Edge() *Edge {
+	return sr.edges[0]
+}
+
+// Edges returns the edge each pattern matched, in the order the patterns were joined
+func (sr *SearchResults) Edges() []*Edge {
+	return sr.edges
 }
 
+// Get returns the value bound to the named variable, and whether the variable was bound at all
+func (sr *SearchResults) Get(name string) (interface{}, bool) {
+	if variable, ok := sr.variables[name]; ok {
+		return variable.value, true
+	}
+
+	return nil, false
+}
+
+// Bindings returns every bound variable, keyed by variable name
+func (sr *SearchResults) Bindings() map[string]interface{} {
+	bindings := make(map[string]interface{}, len(sr.variables))
+
+	for name, variable := range sr.variables {
+		bindings[name] = variable.value
+	}
+
+	return bindings
+}
+
+// merge combines the bindings of two results, or returns nil if they bind a shared variable to different values
+func (sr *SearchResults) merge(other *SearchResults) *SearchResults {
+	variables := make(map[string]*VariableResult, len(sr.variables) + len(other.variables))
+
+	for name, variable := range sr.variables {
+		variables[name] = variable
+	}
+
+	for name, variable := range other.variables {
+		if existing, ok := variables[name]; ok && !valuesEqual(existing.value, variable.value) {
+			return nil
+		}
+
+		variables[name] = variable
+	}
+
+	edges := make([]*Edge, 0, len(sr.edges) + len(other.edges))
+	edges = append(edges, sr.edges...)
+	edges = append(edges, other.edges...)
+
+	return &SearchResults{ edges: edges, variables: variables }
+}
+
+// TripleOrder describes how a stream is sorted: by the fields of its edges for edge streams, or by the values of
+// its variables for streams of SearchResults
 type TripleOrder struct {
 	dataFieldOrder []DataField
+	variableOrder []string
 }
 
 func (to TripleOrder) fromEdge(edge *Edge) []byte {
@@ -342,27 +648,26 @@ func (to TripleOrder) fromEdge(edge *Edge) []byte {
 		case PREDICATE:
 			comparisonTuple[i] = edge.predicate
 		case OBJECT:
-			comparisonTuple[i] = edge.object
+			comparisonTuple[i] = encodeValue(edge.object)
 		}
 	}
 
 	return comparisonTuple.Pack()
 }
 
-func (to TripleOrder) fromVariables(variables map[DataField]*VariableResult) []byte {
-	comparisonTuple := make(tuple.Tuple, len(to.dataFieldOrder))
+func (to TripleOrder) fromVariables(variables map[string]*VariableResult) []byte {
+	comparisonTuple := make(tuple.Tuple, len(to.variableOrder))
 
-	for i, dataField := range to.dataFieldOrder {
-		if variables[dataField].value == nil {
+	for i, variable := range to.variableOrder {
+		if variables[variable] == nil {
 			panic(fmt.Sprintf("bad argument, variables asked to sort on %v without field specified. was %v, sort %v\n",
-				dataField,
+				variable,
 				variables,
-				to.dataFieldOrder))
+				to.variableOrder))
 		}
 
-		comparisonTuple[i] = variables[dataField].value
+		comparisonTuple[i] = encodeValue(variables[variable].value)
 	}
 
 	return comparisonTuple.Pack()
 }
-
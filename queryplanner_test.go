@@ -0,0 +1,311 @@
+package simplegraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func friendsGraph() *SimpleGraph {
+	graph := NewSimpleGraph(NewMemoryGraph())
+
+	friend := []byte("friend")
+	_ = graph.AddEdges([]Edge{
+		NewEdge([]byte("paul"), friend, []byte("anne")),
+		NewEdge([]byte("paul"), friend, []byte("bob")),
+		NewEdge([]byte("paul"), friend, []byte("carl")),
+		NewEdge([]byte("anne"), friend, []byte("dave")),
+		NewEdge([]byte("anne"), friend, []byte("erin")),
+		NewEdge([]byte("bob"), friend, []byte("erin")),
+		NewEdge([]byte("carl"), friend, []byte("carl")),
+		NewEdge([]byte("dave"), friend, []byte("jess")),
+		NewEdge([]byte("erin"), friend, []byte("jess")),
+		NewEdge([]byte("erin"), friend, []byte("dave")),
+		NewEdge([]byte("erin"), []byte("works at"), []byte("acme")),
+		NewEdge([]byte("dave"), []byte("works at"), []byte("acme")),
+	})
+
+	return graph
+}
+
+// collectBindings drains a search and renders each result's bindings in a stable, comparable form
+func collectBindings(t *testing.T, stream *SearchStream) []string {
+	var got []string
+
+	for result := range stream.Results() {
+		bindings := result.Bindings()
+
+		var names []string
+		for name := range bindings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		rendered := ""
+		for _, name := range names {
+			value := bindings[name]
+			if text, ok := value.([]byte); ok {
+				value = string(text)
+			}
+
+			rendered += fmt.Sprintf("%v=%v ", name, value)
+		}
+
+		got = append(got, rendered)
+	}
+
+	if e := stream.Err(); e != nil {
+		t.Fatalf("stream failed: %v", e)
+	}
+
+	sort.Strings(got)
+	return got
+}
+
+func TestSimpleGraph_Match(t *testing.T) {
+	graph := friendsGraph()
+
+	friend := []byte("friend")
+
+	tests := []struct {
+		name     string
+		patterns []Query
+		want     []string
+	}{
+		{
+			name:     "it matches a single pattern",
+			patterns: []Query{NewQuery().WithSubject([]byte("paul")).WithPredicate(friend).WithObjectVariable("x")},
+			want:     []string{"x=anne ", "x=bob ", "x=carl "},
+		},
+		{
+			name: "it follows a chain of patterns",
+			patterns: []Query{
+				NewQuery().WithSubject([]byte("paul")).WithPredicate(friend).WithObjectVariable("x"),
+				NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+				NewQuery().WithSubjectVariable("y").WithPredicate(friend).WithObject([]byte("jess")),
+			},
+			want: []string{"x=anne y=dave ", "x=anne y=erin ", "x=bob y=erin "},
+		},
+		{
+			name: "it joins duplicate keys on both sides",
+			patterns: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+				NewQuery().WithSubjectVariable("y").WithPredicate([]byte("works at")).WithObjectVariable("company"),
+			},
+			want: []string{
+				"company=acme x=anne y=dave ", "company=acme x=anne y=erin ",
+				"company=acme x=bob y=erin ", "company=acme x=erin y=dave ",
+			},
+		},
+		{
+			name: "it re-sorts results that can't be merged in their current order",
+			patterns: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObjectVariable("c"),
+				NewQuery().WithSubjectVariable("y").WithPredicate([]byte("works at")).WithObjectVariable("d"),
+			},
+			want: []string{"c=acme d=acme x=erin y=dave "},
+		},
+		{
+			name: "it joins a star on a shared subject",
+			patterns: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObject([]byte("jess")),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObject([]byte("acme")),
+			},
+			want: []string{"x=dave ", "x=erin "},
+		},
+		{
+			name: "it requires a repeated variable to bind the same value",
+			patterns: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("x"),
+			},
+			want: []string{"x=carl "},
+		},
+		{
+			name: "it takes the product of patterns without shared variables",
+			patterns: []Query{
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObject([]byte("acme")),
+				NewQuery().WithSubject([]byte("paul")).WithPredicate(friend).WithObjectVariable("y"),
+			},
+			want: []string{
+				"x=dave y=anne ", "x=dave y=bob ", "x=dave y=carl ",
+				"x=erin y=anne ", "x=erin y=bob ", "x=erin y=carl ",
+			},
+		},
+		{
+			name: "it finds nothing when a pattern has no matches",
+			patterns: []Query{
+				NewQuery().WithSubject([]byte("paul")).WithPredicate(friend).WithObjectVariable("x"),
+				NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObject([]byte("initech")),
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, e := graph.Match(context.Background(), tt.patterns...)
+
+			if e != nil {
+				t.Fatalf("Match() error = %v", e)
+			}
+
+			if got := collectBindings(t, stream); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_generateQueryPlan(t *testing.T) {
+	friend := []byte("friend")
+
+	plan, e := (&costModel{}).generateQueryPlan(
+		NewQuery().WithSubject([]byte("paul")).WithPredicate(friend).WithObjectVariable("x"),
+		NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+		NewQuery().WithSubjectVariable("y").WithPredicate(friend).WithObject([]byte("jess")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	// stage 3 joins on y, but the output of stage 2 is in x order. stage 3 is small, so it's hashed
+	stage3, ok := plan.source.(*hashJoin)
+	if !ok || !reflect.DeepEqual(stage3.joinOrder.variableOrder, []string{"y"}) {
+		t.Fatalf("expected a final hash join on y, got %#v", plan.source)
+	}
+
+	if scan, ok := stage3.joins[1].(*indexScanSource); !ok || scan.idx.ordering[2] != SUBJECT {
+		t.Errorf("expected stage 3 to scan pos or ops, got %#v", stage3.joins[1])
+	}
+
+	stage2, ok := stage3.joins[0].(*mergeJoin)
+	if !ok || !reflect.DeepEqual(stage2.joinOrder.variableOrder, []string{"x"}) {
+		t.Fatalf("expected a merge join on x, got %#v", stage3.joins[0])
+	}
+
+	if scan, ok := stage2.joins[1].(*indexScanSource); !ok || scan.idx != Indices["pso"] {
+		t.Errorf("expected stage 2 to scan pso, got %#v", stage2.joins[1])
+	}
+
+	// when neither side fits in memory to hash, the join on x is re-sorted by y for a merge join
+	plan, _ = (&costModel{ memoryBudget: 1 }).generateQueryPlan(
+		NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObjectVariable("company"),
+		NewQuery().WithSubjectVariable("y").WithPredicate(friend).WithObjectVariable("z"))
+
+	if join, ok := plan.source.(*mergeJoin); !ok {
+		t.Errorf("expected a merge join, got %#v", plan.source)
+	} else if _, ok := join.joins[0].(*bufferSortedSource); !ok {
+		t.Errorf("expected the join on x to be sorted by y, got %#v", join.joins[0])
+	}
+
+	if _, e := (&costModel{}).generateQueryPlan(); e == nil {
+		t.Errorf("expected an error planning an empty query")
+	}
+}
+
+func Test_generateQueryPlanWithStatistics(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+
+	var edges []Edge
+	for i := 0; i < 200; i++ {
+		player := []byte(fmt.Sprintf("player %v", i))
+		edges = append(edges, NewEdge(player, []byte("played for"), []byte(fmt.Sprintf("team %v", i % 20))))
+	}
+	edges = append(edges, NewEdge([]byte("player 7"), []byte("mvp"), []byte("1990")))
+	_ = graph.AddEdges(edges)
+
+	if _, e := graph.Analyze(context.Background()); e != nil {
+		t.Fatal(e)
+	}
+
+	common := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("played for")).WithObjectVariable("team")
+	rare := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("mvp")).WithObjectVariable("year")
+
+	plan, e := graph.costModel().generateQueryPlan(common, rare)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	join, ok := plan.source.(*mergeJoin)
+	if !ok {
+		t.Fatalf("expected a merge join, got %#v", plan.source)
+	}
+
+	if scan, ok := join.joins[0].(*indexScanSource); !ok || !reflect.DeepEqual(*scan.query, rare) {
+		t.Errorf("expected the rare predicate to be scanned first, got %#v", join.joins[0])
+	}
+
+	if rows := join.getEstimate().rows; rows < 0.5 || rows > 2 {
+		t.Errorf("expected the join to be estimated at about 1 row, got %v", rows)
+	}
+
+	// a fixed object is estimated from the predicate's histogram
+	teamQuery := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("played for")).WithObject([]byte("team 3"))
+	if rows := graph.costModel().scanEstimate(teamQuery).rows; rows != 10 {
+		t.Errorf("expected 10 players per team, got %v", rows)
+	}
+
+	stream, _ := graph.Match(context.Background(), common, rare)
+	if got, want := collectBindings(t, stream), []string{"team=team 7 x=player 7 year=1990 "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %v, want %v", got, want)
+	}
+}
+
+func Test_generateQueryPlanCostsRightSorts(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+
+	var edges []Edge
+	for i := 0; i < 200; i++ {
+		edges = append(edges, NewEdge([]byte(fmt.Sprintf("player %v", i)), []byte("age"), int64(20 + i % 20)))
+	}
+	edges = append(edges, NewEdge([]byte("player 7"), []byte("mvp"), int64(1990)))
+	_ = graph.AddEdges(edges)
+
+	if _, e := graph.Analyze(context.Background()); e != nil {
+		t.Fatal(e)
+	}
+
+	// both scans read a range, so come out in object order rather than x order. no mvp falls in the range, so that
+	// scan is estimated at less than a row and sorting it costs next to nothing, but the age scan would have to be
+	// sorted too, so hashing wins
+	mvps := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("mvp")).WithObjectRange(Between(int64(1950), int64(1960)))
+	ages := NewQuery().WithSubjectVariable("x").WithPredicate([]byte("age")).WithObjectRange(AtLeast(int64(25)))
+
+	plan, e := graph.costModel().generateQueryPlan(mvps, ages)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	join, ok := plan.source.(*hashJoin)
+	if !ok {
+		t.Fatalf("expected a hash join, got %#v", plan.source)
+	}
+
+	if left := join.joins[0].getEstimate().rows; left >= 1 {
+		t.Errorf("expected the mvp scan to be estimated at under a row, got %v", left)
+	}
+
+	stream, _ := graph.Match(context.Background(), mvps.WithObjectRange(Between(int64(1980), int64(2000))), ages)
+	if got, want := collectBindings(t, stream), []string{"x=player 7 "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %v, want %v", got, want)
+	}
+}
+
+func TestSimpleGraph_MatchSpillsSorts(t *testing.T) {
+	graph := friendsGraph()
+	graph.SetSortMemoryBudget(1)
+
+	friend := []byte("friend")
+
+	stream, _ := graph.Match(context.Background(),
+		NewQuery().WithSubjectVariable("x").WithPredicate(friend).WithObjectVariable("y"),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("works at")).WithObjectVariable("c"),
+		NewQuery().WithSubjectVariable("y").WithPredicate([]byte("works at")).WithObjectVariable("d"))
+
+	if got, want := collectBindings(t, stream), []string{"c=acme d=acme x=erin y=dave "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %v, want %v", got, want)
+	}
+}
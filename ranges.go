@@ -0,0 +1,203 @@
+package simplegraph
+
+import (
+	"errors"
+	"fmt"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"strings"
+)
+
+// ValueRange constrains an object to a range of values of one type, e.g. GreaterThan(int64(10)) matches int64
+// objects above 10, but no floats or strings. Because indices sort objects by value, a pattern with a range is
+// answered by scanning just the matching keys.
+type ValueRange struct {
+	lower, upper                   interface{}
+	lowerInclusive, upperInclusive bool
+	prefix                         interface{}
+}
+
+func GreaterThan(value interface{}) ValueRange {
+	return ValueRange{ lower: value }
+}
+
+func AtLeast(value interface{}) ValueRange {
+	return ValueRange{ lower: value, lowerInclusive: true }
+}
+
+func LessThan(value interface{}) ValueRange {
+	return ValueRange{ upper: value }
+}
+
+func AtMost(value interface{}) ValueRange {
+	return ValueRange{ upper: value, upperInclusive: true }
+}
+
+// Between matches values from lower to upper, both inclusive
+func Between(lower, upper interface{}) ValueRange {
+	return ValueRange{ lower: lower, upper: upper, lowerInclusive: true, upperInclusive: true }
+}
+
+// StartsWith matches strings, or byte slices, beginning with prefix
+func StartsWith(prefix interface{}) ValueRange {
+	return ValueRange{ prefix: prefix }
+}
+
+func (r ValueRange) validate() error {
+	if r.prefix != nil {
+		switch r.prefix.(type) {
+		case string, []byte:
+			return nil
+		default:
+			return fmt.Errorf("a prefix range needs a string or []byte, not %T", r.prefix)
+		}
+	}
+
+	if r.lower == nil && r.upper == nil {
+		return errors.New("a range needs at least one bound")
+	}
+
+	for _, bound := range []interface{}{ r.lower, r.upper } {
+		if bound == nil {
+			continue
+		}
+
+		if e := validateValue(bound); e != nil {
+			return e
+		}
+	}
+
+	if r.lower != nil && r.upper != nil {
+		if lowerCodes, upperCodes := typeCodes(r.lower), typeCodes(r.upper); lowerCodes != upperCodes {
+			return fmt.Errorf("range bounds %T and %T are different types", r.lower, r.upper)
+		}
+	}
+
+	return nil
+}
+
+// keyRange returns the keys under prefix whose next tuple element is in the range
+func (r ValueRange) keyRange(prefix []byte) (begin, end []byte) {
+	if r.prefix != nil {
+		encoded := encodeElement(r.prefix)
+
+		// drop the terminator, so longer values extend the encoding
+		begin = join(prefix, encoded[:len(encoded) - 1])
+		end, _ = fdb.Strinc(begin)
+
+		return begin, end
+	}
+
+	var codes [2]byte
+	if r.lower != nil {
+		codes = typeCodes(r.lower)
+	} else {
+		codes = typeCodes(r.upper)
+	}
+
+	// keys always continue after the ranged element, with a type code below 0xff, so appending 0xff to an element
+	// sorts after every key holding it
+	switch {
+	case r.lower == nil:
+		begin = join(prefix, codes[:1])
+	case r.lowerInclusive:
+		begin = join(prefix, encodeElement(r.lower))
+	default:
+		begin = join(prefix, encodeElement(r.lower), []byte{ 0xff })
+	}
+
+	switch {
+	case r.upper == nil:
+		end = join(prefix, codes[1:])
+	case r.upperInclusive:
+		end = join(prefix, encodeElement(r.upper), []byte{ 0xff })
+	default:
+		end = join(prefix, encodeElement(r.upper))
+	}
+
+	return begin, end
+}
+
+// contains reports whether a value is in the range
+func (r ValueRange) contains(value interface{}) bool {
+	if r.prefix != nil {
+		switch prefix := r.prefix.(type) {
+		case string:
+			v, ok := value.(string)
+			return ok && strings.HasPrefix(v, prefix)
+		case []byte:
+			v, ok := value.([]byte)
+			return ok && strings.HasPrefix(string(v), string(prefix))
+		}
+	}
+
+	bound := r.lower
+	if bound == nil {
+		bound = r.upper
+	}
+
+	if typeCodes(value) != typeCodes(bound) {
+		return false
+	}
+
+	if r.lower != nil {
+		if c := compareValues(value, r.lower); c < 0 || (c == 0 && !r.lowerInclusive) {
+			return false
+		}
+	}
+
+	if r.upper != nil {
+		if c := compareValues(value, r.upper); c > 0 || (c == 0 && !r.upperInclusive) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r ValueRange) String() string {
+	switch {
+	case r.prefix != nil:
+		return fmt.Sprintf("starts with %v", formatValue(r.prefix))
+	case r.lower != nil && r.upper != nil:
+		return fmt.Sprintf("between %v and %v", formatValue(r.lower), formatValue(r.upper))
+	case r.lower != nil && r.lowerInclusive:
+		return fmt.Sprintf(">= %v", formatValue(r.lower))
+	case r.lower != nil:
+		return fmt.Sprintf("> %v", formatValue(r.lower))
+	case r.upperInclusive:
+		return fmt.Sprintf("<= %v", formatValue(r.upper))
+	default:
+		return fmt.Sprintf("< %v", formatValue(r.upper))
+	}
+}
+
+func encodeElement(value interface{}) []byte {
+	return tuple.Tuple{ encodeValue(value) }.Pack()
+}
+
+// typeCodes returns the first tuple type code used by values of the same type as value, and the first code after
+func typeCodes(value interface{}) [2]byte {
+	code := encodeElement(value)[0]
+
+	switch {
+	case code >= 0x0b && code <= 0x1d:
+		// integers use a code per length and sign
+		return [2]byte{ 0x0b, 0x1e }
+	case code == 0x26 || code == 0x27:
+		// false and true
+		return [2]byte{ 0x26, 0x28 }
+	default:
+		return [2]byte{ code, code + 1 }
+	}
+}
+
+func join(parts ...[]byte) []byte {
+	var joined []byte
+
+	for _, part := range parts {
+		joined = append(joined, part...)
+	}
+
+	return joined
+}
@@ -0,0 +1,160 @@
+package simplegraph
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func seasonsGraph() *SimpleGraph {
+	graph := NewSimpleGraph(NewMemoryGraph())
+	seasons := []byte("seasons played")
+
+	_ = graph.AddEdges([]Edge{
+		NewEdge([]byte("kevin garnett"), seasons, int64(21)),
+		NewEdge([]byte("larry bird"), seasons, int64(13)),
+		NewEdge([]byte("kevin mchale"), seasons, int64(13)),
+		NewEdge([]byte("bill walton"), seasons, int64(10)),
+		NewEdge([]byte("len bias"), seasons, int64(0)),
+		NewEdge([]byte("rookie"), seasons, int64(-1)),
+		NewEdge([]byte("estimate"), seasons, 12.5),
+		NewEdge([]byte("unknown"), seasons, "lots"),
+		NewEdge([]byte("kevin garnett"), []byte("team"), "Timberwolves"),
+		NewEdge([]byte("larry bird"), []byte("team"), "Celtics"),
+		NewEdge([]byte("kevin mchale"), []byte("team"), "Celtics"),
+		NewEdge([]byte("bill walton"), []byte("team"), "Clippers"),
+		NewEdge([]byte("larry bird"), []byte("drafted"), time.Date(1978, time.June, 9, 0, 0, 0, 0, time.UTC)),
+		NewEdge([]byte("kevin garnett"), []byte("drafted"), time.Date(1995, time.June, 28, 0, 0, 0, 0, time.UTC)),
+	})
+
+	return graph
+}
+
+func TestSimpleGraph_ObjectRanges(t *testing.T) {
+	graph := seasonsGraph()
+	seasons := []byte("seasons played")
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{
+			"it matches greater than",
+			NewQuery().WithSubjectVariable("x").WithPredicate(seasons).WithObjectRange(GreaterThan(int64(10))),
+			[]string{"kevin garnett", "kevin mchale", "larry bird"},
+		},
+		{
+			"it matches at least",
+			NewQuery().WithSubjectVariable("x").WithPredicate(seasons).WithObjectRange(AtLeast(int64(10))),
+			[]string{"bill walton", "kevin garnett", "kevin mchale", "larry bird"},
+		},
+		{
+			"it matches less than, including negative numbers",
+			NewQuery().WithSubjectVariable("x").WithPredicate(seasons).WithObjectRange(LessThan(int64(10))),
+			[]string{"len bias", "rookie"},
+		},
+		{
+			"it matches at most",
+			NewQuery().WithSubjectVariable("x").WithPredicate(seasons).WithObjectRange(AtMost(int64(0))),
+			[]string{"len bias", "rookie"},
+		},
+		{
+			"it matches between, inclusive",
+			NewQuery().WithSubjectVariable("x").WithPredicate(seasons).WithObjectRange(Between(int64(10), int64(13))),
+			[]string{"bill walton", "kevin mchale", "larry bird"},
+		},
+		{
+			"it only matches the bound's type",
+			NewQuery().WithSubjectVariable("x").WithPredicate(seasons).WithObjectRange(GreaterThan(10.0)),
+			[]string{"estimate"},
+		},
+		{
+			"it matches string prefixes",
+			NewQuery().WithSubjectVariable("x").WithPredicate([]byte("team")).WithObjectRange(StartsWith("C")),
+			[]string{"bill walton", "kevin mchale", "larry bird"},
+		},
+		{
+			"it matches timestamps",
+			NewQuery().WithSubjectVariable("x").WithPredicate([]byte("drafted")).
+				WithObjectRange(AtLeast(time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC))),
+			[]string{"kevin garnett"},
+		},
+		{
+			"it matches ranges without a fixed predicate",
+			NewQuery().WithSubjectVariable("x").WithObjectRange(StartsWith("Tim")),
+			[]string{"kevin garnett"},
+		},
+		{
+			"it matches ranges with a fixed subject",
+			NewQuery().WithSubject([]byte("larry bird")).WithPredicateVariable("x").WithObjectRange(StartsWith("Cel")),
+			[]string{"team"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, e := graph.ExplainAnalyze(context.Background(), tt.query)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			// the range is answered by the scan, not by filtering what it reads
+			if plan.Actual.KeysScanned != int64(len(tt.want)) {
+				t.Errorf("expected %v keys scanned, got\n%v", len(tt.want), plan)
+			}
+
+			stream, _ := graph.Match(context.Background(), tt.query)
+
+			var got []string
+			for result := range stream.Results() {
+				x, _ := result.Get("x")
+				got = append(got, string(x.([]byte)))
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleGraph_ObjectRangesJoin(t *testing.T) {
+	graph := seasonsGraph()
+
+	stream, e := graph.Match(context.Background(),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("team")).WithObject("Celtics"),
+		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("seasons played")).
+			WithObjectVariable("seasons").WithObjectRange(AtLeast(int64(13))))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if got, want := collectBindings(t, stream), []string{"seasons=13 x=kevin mchale ", "seasons=13 x=larry bird "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Match() = %v, want %v", got, want)
+	}
+}
+
+func TestValueRange_validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{"it rejects mixed types", NewQuery().WithSubjectVariable("x").WithObjectRange(Between(int64(1), "z"))},
+		{"it rejects prefixes of other types", NewQuery().WithSubjectVariable("x").WithObjectRange(StartsWith(int64(1)))},
+		{"it rejects a fixed object with a range", NewQuery().WithSubjectVariable("x").WithObject("a").WithObjectRange(StartsWith("a"))},
+		{"it rejects unsupported bounds", NewQuery().WithSubjectVariable("x").WithObjectRange(LessThan(uint8(1)))},
+		{"it rejects times that can't be stored", NewQuery().WithSubjectVariable("x").WithObjectRange(LessThan(time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, e := NewSimpleGraph(NewMemoryGraph()).Match(context.Background(), tt.query); e == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,936 @@
+package simplegraph
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+// RDF terms map onto edges as follows. IRIs become []byte holding the IRI, so resources read back as the same bytes
+// wherever they appear. Blank nodes only name a node within one document, so they become []byte holding _:label with
+// a random suffix for each import, and documents that use the same label don't share the node. Literals become
+// objects of the closest type an edge can hold: int64 for xsd:integer and its subtypes, float64 for xsd:decimal and
+// xsd:double, bool for xsd:boolean, time.Time for xsd:dateTime, and a string for everything else. Language tags and
+// other datatypes are dropped.
+const (
+	xsdNamespace = "http://www.w3.org/2001/XMLSchema#"
+	rdfType      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+)
+
+func literalValue(lexical, datatype string) (interface{}, error) {
+	switch strings.TrimPrefix(datatype, xsdNamespace) {
+	case "integer", "int", "long", "short", "byte", "nonNegativeInteger", "nonPositiveInteger", "positiveInteger",
+		"negativeInteger", "unsignedInt", "unsignedShort", "unsignedByte":
+		if !strings.HasPrefix(datatype, xsdNamespace) {
+			break
+		}
+
+		return strconv.ParseInt(strings.TrimPrefix(lexical, "+"), 10, 64)
+	case "decimal", "double", "float":
+		if !strings.HasPrefix(datatype, xsdNamespace) {
+			break
+		}
+
+		return strconv.ParseFloat(lexical, 64)
+	case "boolean":
+		if !strings.HasPrefix(datatype, xsdNamespace) {
+			break
+		}
+
+		return strconv.ParseBool(lexical)
+	case "dateTime":
+		if !strings.HasPrefix(datatype, xsdNamespace) {
+			break
+		}
+
+		t, e := time.Parse(time.RFC3339Nano, lexical)
+		return t.UTC(), e
+	}
+
+	return lexical, nil
+}
+
+// literalForm is the lexical form and datatype a typed object is written with. Strings are plain literals, and
+// []byte isn't a literal at all.
+func literalForm(value interface{}) (lexical, datatype string) {
+	switch v := value.(type) {
+	case string:
+		return v, ""
+	case int:
+		return strconv.Itoa(v), xsdNamespace + "integer"
+	case int64:
+		return strconv.FormatInt(v, 10), xsdNamespace + "integer"
+	case float64:
+		return strconv.FormatFloat(v, 'E', -1, 64), xsdNamespace + "double"
+	case bool:
+		return strconv.FormatBool(v), xsdNamespace + "boolean"
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), xsdNamespace + "dateTime"
+	case tuple.UUID:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), ""
+	default:
+		return fmt.Sprint(v), ""
+	}
+}
+
+// writeIRI writes a resource as an IRI reference, or a blank node if it's labelled like one. Fields that aren't
+// IRIs, like the names in testdata/nba.csv, come out as relative IRIs with their unsafe characters escaped.
+func writeIRI(w *bufio.Writer, resource []byte) {
+	if isBlankNode(resource) {
+		_, _ = w.Write(resource)
+		return
+	}
+
+	_ = w.WriteByte('<')
+
+	for _, r := range string(resource) {
+		if r <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r) {
+			_, _ = fmt.Fprintf(w, "\\u%04X", r)
+		} else {
+			_, _ = w.WriteRune(r)
+		}
+	}
+
+	_ = w.WriteByte('>')
+}
+
+func isBlankNode(resource []byte) bool {
+	if len(resource) < 3 || resource[0] != '_' || resource[1] != ':' {
+		return false
+	}
+
+	for _, r := range string(resource[2:]) {
+		if !isNameRune(r) {
+			return false
+		}
+	}
+
+	return resource[len(resource) - 1] != '.'
+}
+
+func writeString(w *bufio.Writer, s string) {
+	_ = w.WriteByte('"')
+
+	for _, r := range s {
+		switch r {
+		case '"':
+			_, _ = w.WriteString(`\"`)
+		case '\\':
+			_, _ = w.WriteString(`\\`)
+		case '\n':
+			_, _ = w.WriteString(`\n`)
+		case '\r':
+			_, _ = w.WriteString(`\r`)
+		case '\t':
+			_, _ = w.WriteString(`\t`)
+		default:
+			_, _ = w.WriteRune(r)
+		}
+	}
+
+	_ = w.WriteByte('"')
+}
+
+// writeObject writes an edge's object. writeDatatype writes the literal's datatype, so Turtle can shorten it.
+func writeObject(w *bufio.Writer, object interface{}, writeDatatype func(iri string)) {
+	if resource, ok := object.([]byte); ok {
+		writeIRI(w, resource)
+		return
+	}
+
+	lexical, datatype := literalForm(object)
+	writeString(w, lexical)
+
+	if datatype != "" {
+		_, _ = w.WriteString("^^")
+		writeDatatype(datatype)
+	}
+}
+
+const eof = -1
+
+// rdfParser reads Turtle, or with turtle unset, the N-Triples subset of it. It parses a statement at a time, and
+// queues up the edges it holds.
+type rdfParser struct {
+	in     *bufio.Reader
+	turtle bool
+
+	ahead []rune
+	line  int
+
+	// lineStart is set when the last rune read ended a line
+	lineStart bool
+
+	prefixes map[string]string
+	base     string
+
+	// scope is a random suffix for the document's blank node labels, which also names its anonymous nodes
+	scope     string
+	anonymous int
+
+	pending []Edge
+	err     error
+
+	// readErr is the first error reading the input, other than its end, which the parser otherwise sees as the end
+	readErr error
+}
+
+// blankNodeScope returns a random scope for the blank nodes of a document, so they don't clash with those of others
+func blankNodeScope() string {
+	random := make([]byte, 4)
+	_, _ = rand.Read(random)
+
+	return hex.EncodeToString(random)
+}
+
+func newRDFParser(r io.Reader, turtle bool) *rdfParser {
+	return &rdfParser{
+		in:              bufio.NewReader(r),
+		turtle:          turtle,
+		line:            1,
+		prefixes:        make(map[string]string),
+		scope:           blankNodeScope(),
+	}
+}
+
+func (p *rdfParser) read() (Edge, error) {
+	for len(p.pending) == 0 && p.err == nil {
+		p.skipSpace()
+
+		if p.peek() == eof {
+			p.err = io.EOF
+		} else {
+			p.err = p.statement()
+		}
+
+		// whatever the parser made of input cut short, the read error is what went wrong
+		if p.err != nil && p.readErr != nil {
+			p.err = p.readErr
+		}
+	}
+
+	if len(p.pending) == 0 {
+		return Edge{}, p.err
+	}
+
+	edge := p.pending[0]
+	p.pending = p.pending[1:]
+	return edge, nil
+}
+
+func (p *rdfParser) peekAt(i int) rune {
+	for len(p.ahead) <= i {
+		r, _, e := p.in.ReadRune()
+
+		if e != nil {
+			if e != io.EOF && p.readErr == nil {
+				p.readErr = e
+			}
+
+			return eof
+		}
+
+		p.ahead = append(p.ahead, r)
+	}
+
+	return p.ahead[i]
+}
+
+func (p *rdfParser) peek() rune {
+	return p.peekAt(0)
+}
+
+func (p *rdfParser) next() rune {
+	r := p.peek()
+
+	if r != eof {
+		p.ahead = p.ahead[1:]
+	}
+
+	if r == '\n' {
+		p.line++
+	}
+
+	p.lineStart = r == '\n'
+	return r
+}
+
+// linesRead counts the lines read so far, including one that's only partly read
+func (p *rdfParser) linesRead() int {
+	if p.lineStart {
+		return p.line - 1
+	}
+
+	return p.line
+}
+
+func (p *rdfParser) errorf(format string, args ...interface{}) error {
+	return &LineError{ Line: p.line, Err: fmt.Errorf(format, args...) }
+}
+
+func (p *rdfParser) expect(r rune) error {
+	p.skipSpace()
+
+	if found := p.next(); found != r {
+		return p.errorf("expected %q, found %v", r, describeRune(found))
+	}
+
+	return nil
+}
+
+func describeRune(r rune) string {
+	if r == eof {
+		return "the end of the input"
+	}
+
+	return strconv.QuoteRune(r)
+}
+
+// skipSpace skips whitespace and comments
+func (p *rdfParser) skipSpace() {
+	for {
+		switch r := p.peek(); {
+		case r == '#':
+			for r != '\n' && r != eof {
+				r = p.next()
+			}
+		case r != eof && unicode.IsSpace(r):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+func (p *rdfParser) statement() error {
+	if !p.turtle {
+		subject, e := p.resource(false)
+
+		if e != nil {
+			return e
+		}
+
+		predicate, e := p.resource(false)
+
+		if e != nil {
+			return e
+		}
+
+		if e := p.objectList(subject, predicate); e != nil {
+			return e
+		}
+
+		return p.expect('.')
+	}
+
+	if p.peek() == '@' {
+		p.next()
+		return p.directive(p.word(), true)
+	}
+
+	if word := p.peekWord(); strings.EqualFold(word, "prefix") || strings.EqualFold(word, "base") {
+		return p.directive(strings.ToLower(p.word()), false)
+	}
+
+	var subject []byte
+	var e error
+
+	if p.peek() == '[' {
+		// a blank node property list can stand alone as a statement
+		if subject, e = p.blankNodePropertyList(); e != nil {
+			return e
+		}
+
+		if p.skipSpace(); p.peek() == '.' {
+			p.next()
+			return nil
+		}
+	} else if subject, e = p.resource(true); e != nil {
+		return e
+	}
+
+	if e := p.predicateObjectList(subject); e != nil {
+		return e
+	}
+
+	return p.expect('.')
+}
+
+// directive reads the rest of @prefix, @base, or their SPARQL style forms, which don't end with a full stop
+func (p *rdfParser) directive(name string, dotted bool) error {
+	p.skipSpace()
+
+	switch name {
+	case "prefix":
+		var prefix strings.Builder
+
+		for p.peek() != ':' {
+			r := p.next()
+
+			if !isNameRune(r) {
+				return p.errorf("expected a prefix, found %v", describeRune(r))
+			}
+
+			prefix.WriteRune(r)
+		}
+
+		p.next()
+		p.skipSpace()
+		namespace, e := p.iriRef()
+
+		if e != nil {
+			return e
+		}
+
+		p.prefixes[prefix.String()] = namespace
+	case "base":
+		base, e := p.iriRef()
+
+		if e != nil {
+			return e
+		}
+
+		p.base = base
+	default:
+		return p.errorf("unknown directive %q", name)
+	}
+
+	if dotted {
+		return p.expect('.')
+	}
+
+	return nil
+}
+
+// word reads a run of letters
+func (p *rdfParser) word() string {
+	var word strings.Builder
+
+	for unicode.IsLetter(p.peek()) {
+		word.WriteRune(p.next())
+	}
+
+	return word.String()
+}
+
+// peekWord returns the letters ahead, if they're followed by whitespace, without reading them
+func (p *rdfParser) peekWord() string {
+	var word strings.Builder
+
+	for i := 0; ; i++ {
+		r := p.peekAt(i)
+
+		if unicode.IsLetter(r) {
+			word.WriteRune(r)
+		} else if r != eof && unicode.IsSpace(r) {
+			return word.String()
+		} else {
+			return ""
+		}
+	}
+}
+
+func (p *rdfParser) predicateObjectList(subject []byte) error {
+	for {
+		p.skipSpace()
+		predicate, e := p.verb()
+
+		if e != nil {
+			return e
+		}
+
+		if e := p.objectList(subject, predicate); e != nil {
+			return e
+		}
+
+		p.skipSpace()
+		if p.peek() != ';' {
+			return nil
+		}
+
+		for p.peek() == ';' {
+			p.next()
+			p.skipSpace()
+		}
+
+		// a trailing semicolon is allowed
+		if r := p.peek(); r == '.' || r == ']' {
+			return nil
+		}
+	}
+}
+
+func (p *rdfParser) verb() ([]byte, error) {
+	if p.peek() == 'a' {
+		if r := p.peekAt(1); r == '<' || r == '[' || r == '_' || r == '"' || unicode.IsSpace(r) {
+			p.next()
+			return []byte(rdfType), nil
+		}
+	}
+
+	return p.resource(false)
+}
+
+func (p *rdfParser) objectList(subject, predicate []byte) error {
+	for {
+		p.skipSpace()
+		object, e := p.object()
+
+		if e != nil {
+			return e
+		}
+
+		p.pending = append(p.pending, NewEdge(subject, predicate, object))
+
+		if !p.turtle {
+			return nil
+		}
+
+		if p.skipSpace(); p.peek() != ',' {
+			return nil
+		}
+
+		p.next()
+	}
+}
+
+func (p *rdfParser) object() (interface{}, error) {
+	p.skipSpace()
+
+	switch r := p.peek(); {
+	case r == '"' || (r == '\'' && p.turtle):
+		return p.literal()
+	case p.turtle && (r == '+' || r == '-' || r == '.' || (r >= '0' && r <= '9')):
+		return p.number()
+	case r == '[' && p.turtle:
+		return p.blankNodePropertyList()
+	case p.turtle && (r == 't' || r == 'f'):
+		if word := p.peekBoolean(); word != "" {
+			for range word {
+				p.next()
+			}
+
+			return word == "true", nil
+		}
+	}
+
+	return p.resource(p.turtle)
+}
+
+// peekBoolean returns true or false if either is ahead, and isn't the start of a longer name
+func (p *rdfParser) peekBoolean() string {
+	for _, word := range []string{ "true", "false" } {
+		matches := true
+
+		for i, r := range word {
+			if p.peekAt(i) != r {
+				matches = false
+				break
+			}
+		}
+
+		if after := p.peekAt(len(word)); matches && !isNameRune(after) && after != ':' {
+			return word
+		}
+	}
+
+	return ""
+}
+
+// resource reads an IRI or a blank node label, and in Turtle, a prefixed name
+func (p *rdfParser) resource(allowAnonymous bool) ([]byte, error) {
+	p.skipSpace()
+
+	switch r := p.peek(); {
+	case r == '<':
+		iri, e := p.iriRef()
+		return []byte(iri), e
+	case r == '_' && p.peekAt(1) == ':':
+		p.next()
+		p.next()
+		label := p.name()
+
+		if label == "" {
+			return nil, p.errorf("expected a blank node label")
+		}
+
+		return []byte("_:" + label + "_" + p.scope), nil
+	case r == '[' && allowAnonymous:
+		return p.blankNodePropertyList()
+	case p.turtle && (r == ':' || isNameRune(r)):
+		return p.prefixedName()
+	default:
+		return nil, p.errorf("expected an IRI or blank node, found %v", describeRune(r))
+	}
+}
+
+// blankNodePropertyList reads [] or [ predicate object ; ... ], which describe a new blank node
+func (p *rdfParser) blankNodePropertyList() ([]byte, error) {
+	p.next()
+	p.anonymous++
+	node := []byte("_:anon" + p.scope + "x" + strconv.Itoa(p.anonymous))
+
+	if p.skipSpace(); p.peek() != ']' {
+		if e := p.predicateObjectList(node); e != nil {
+			return nil, e
+		}
+	}
+
+	return node, p.expect(']')
+}
+
+func (p *rdfParser) iriRef() (string, error) {
+	if r := p.next(); r != '<' {
+		return "", p.errorf("expected an IRI, found %v", describeRune(r))
+	}
+
+	var iri strings.Builder
+
+	for {
+		switch r := p.next(); r {
+		case '>':
+			return p.resolve(iri.String()), nil
+		case '\\':
+			escaped, e := p.unicodeEscape()
+
+			if e != nil {
+				return "", e
+			}
+
+			iri.WriteRune(escaped)
+		case eof, '\n':
+			return "", p.errorf("unterminated IRI")
+		default:
+			iri.WriteRune(r)
+		}
+	}
+}
+
+// resolve makes an IRI relative to @base absolute. It only handles IRIs that are fragments or plain paths.
+func (p *rdfParser) resolve(iri string) string {
+	if p.base == "" || strings.Contains(iri, ":") {
+		return iri
+	}
+
+	if strings.HasPrefix(iri, "#") {
+		return strings.SplitN(p.base, "#", 2)[0] + iri
+	}
+
+	return p.base[:strings.LastIndex(p.base, "/") + 1] + iri
+}
+
+func (p *rdfParser) prefixedName() ([]byte, error) {
+	var prefix strings.Builder
+
+	for p.peek() != ':' {
+		r := p.next()
+
+		if !isNameRune(r) {
+			return nil, p.errorf("expected a prefixed name, found %v", describeRune(r))
+		}
+
+		prefix.WriteRune(r)
+	}
+
+	p.next()
+	namespace, ok := p.prefixes[prefix.String()]
+
+	if !ok {
+		return nil, p.errorf("undefined prefix %q", prefix.String())
+	}
+
+	local := p.name()
+	return []byte(namespace + local), nil
+}
+
+// name reads the local part of a prefixed name, or a blank node label. A full stop can't end one, so that it can end
+// the statement.
+func (p *rdfParser) name() string {
+	var name strings.Builder
+
+	for {
+		r := p.peek()
+
+		if r == '\\' && p.turtle {
+			p.next()
+			name.WriteRune(p.next())
+			continue
+		}
+
+		if !isNameRune(r) && r != ':' && r != '%' {
+			return name.String()
+		}
+
+		if r == '.' && !isNameRune(p.peekAt(1)) {
+			return name.String()
+		}
+
+		name.WriteRune(p.next())
+	}
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (p *rdfParser) unicodeEscape() (rune, error) {
+	var length int
+
+	switch r := p.next(); r {
+	case 'u':
+		length = 4
+	case 'U':
+		length = 8
+	default:
+		return 0, p.errorf("invalid escape \\%v", string(r))
+	}
+
+	var digits strings.Builder
+	for i := 0; i < length; i++ {
+		digits.WriteRune(p.next())
+	}
+
+	code, e := strconv.ParseUint(digits.String(), 16, 32)
+
+	if e != nil || !utf8.ValidRune(rune(code)) {
+		return 0, p.errorf("invalid escape \\u%v", digits.String())
+	}
+
+	return rune(code), nil
+}
+
+func (p *rdfParser) literal() (interface{}, error) {
+	start := p.line
+	quote := p.next()
+	long := p.turtle && p.peek() == quote && p.peekAt(1) == quote
+
+	if long {
+		p.next()
+		p.next()
+	}
+
+	var lexical strings.Builder
+
+	for {
+		r := p.next()
+
+		switch {
+		case r == eof || (r == '\n' && !long):
+			return nil, &LineError{ Line: start, Err: errors.New("unterminated string") }
+		case r == quote && !long:
+		case r == quote && p.peek() == quote && p.peekAt(1) == quote:
+			p.next()
+			p.next()
+		case r == '\\':
+			escaped, e := p.stringEscape()
+
+			if e != nil {
+				return nil, e
+			}
+
+			lexical.WriteRune(escaped)
+			continue
+		default:
+			lexical.WriteRune(r)
+			continue
+		}
+
+		break
+	}
+
+	switch p.peek() {
+	case '@':
+		p.next()
+
+		for r := p.peek(); unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-'; r = p.peek() {
+			p.next()
+		}
+	case '^':
+		p.next()
+
+		if e := p.expect('^'); e != nil {
+			return nil, e
+		}
+
+		datatype, e := p.resource(false)
+
+		if e != nil {
+			return nil, e
+		}
+
+		value, e := literalValue(lexical.String(), string(datatype))
+
+		if e != nil {
+			return nil, p.errorf("invalid %v: %v", string(datatype), e)
+		}
+
+		return value, nil
+	}
+
+	return lexical.String(), nil
+}
+
+func (p *rdfParser) stringEscape() (rune, error) {
+	switch r := p.peek(); r {
+	case 'u', 'U':
+		return p.unicodeEscape()
+	default:
+		p.next()
+		escaped, ok := map[rune]rune{ 't': '\t', 'b': '\b', 'n': '\n', 'r': '\r', 'f': '\f', '"': '"', '\'': '\'',
+			'\\': '\\' }[r]
+
+		if !ok {
+			return 0, p.errorf("invalid escape \\%v", string(r))
+		}
+
+		return escaped, nil
+	}
+}
+
+// number reads a bare Turtle integer, decimal or double
+func (p *rdfParser) number() (interface{}, error) {
+	var number strings.Builder
+	integer := true
+
+	if r := p.peek(); r == '+' || r == '-' {
+		number.WriteRune(p.next())
+	}
+
+	digits := func() {
+		for r := p.peek(); r >= '0' && r <= '9'; r = p.peek() {
+			number.WriteRune(p.next())
+		}
+	}
+
+	digits()
+
+	if p.peek() == '.' && p.peekAt(1) >= '0' && p.peekAt(1) <= '9' {
+		integer = false
+		number.WriteRune(p.next())
+		digits()
+	}
+
+	if r := p.peek(); r == 'e' || r == 'E' {
+		integer = false
+		number.WriteRune(p.next())
+
+		if r := p.peek(); r == '+' || r == '-' {
+			number.WriteRune(p.next())
+		}
+
+		digits()
+	}
+
+	if integer {
+		value, e := strconv.ParseInt(number.String(), 10, 64)
+
+		if e != nil {
+			return nil, p.errorf("invalid number %q", number.String())
+		}
+
+		return value, nil
+	}
+
+	value, e := strconv.ParseFloat(number.String(), 64)
+
+	if e != nil {
+		return nil, p.errorf("invalid number %q", number.String())
+	}
+
+	return value, nil
+}
+
+// RDFOptions configure ImportNTriples and ImportTurtle
+type RDFOptions struct {
+	// BatchSize is the number of edges written in each call to AddEdges, 1000 by default
+	BatchSize int
+
+	// DryRun parses the document without writing anything, so takes no more memory for a large one than a real
+	// import. A document can't be read past a syntax error, so only the first is reported, in Malformed.
+	DryRun bool
+}
+
+// importEdges writes the edges read until io.EOF to the graph, in batches
+func (graph *SimpleGraph) importEdges(ctx context.Context, read func() (Edge, error), lines func() int,
+	options RDFOptions) (*ImportReport, error) {
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultImportBatchSize
+	}
+
+	report := &ImportReport{}
+	batch := make([]Edge, 0, options.BatchSize)
+
+	flush := func() error {
+		if !options.DryRun && len(batch) > 0 {
+			if e := graph.AddEdges(batch); e != nil {
+				return e
+			}
+		}
+
+		report.Edges += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		if e := ctx.Err(); e != nil {
+			return report, e
+		}
+
+		edge, e := read()
+		report.Lines = lines()
+
+		var lineError *LineError
+
+		if e == io.EOF {
+			return report, flush()
+		} else if options.DryRun && errors.As(e, &lineError) {
+			report.Malformed = append(report.Malformed, lineError)
+			return report, flush()
+		} else if e != nil {
+			return report, e
+		}
+
+		batch = append(batch, edge)
+
+		if len(batch) == options.BatchSize {
+			if e := flush(); e != nil {
+				return report, e
+			}
+		}
+	}
+}
+
+// exportEdges hands each edge matching the query to write, and returns how many it wrote
+func (graph *SimpleGraph) exportEdges(ctx context.Context, query Query, write func(edge Edge) error) (int, error) {
+	stream, e := graph.GetEdges(ctx, query)
+
+	if e != nil {
+		return 0, e
+	}
+
+	defer stream.Close()
+
+	written := 0
+	for edge := range stream.Edges() {
+		if e := write(*edge); e != nil {
+			return written, e
+		}
+
+		written++
+	}
+
+	return written, stream.Err()
+}
@@ -0,0 +1,323 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, read func() (Edge, error)) []Edge {
+	var edges []Edge
+
+	for {
+		edge, e := read()
+
+		if e != nil {
+			if e.Error() != "EOF" {
+				t.Fatal(e)
+			}
+
+			return edges
+		}
+
+		edges = append(edges, edge)
+	}
+}
+
+func TestTurtleReader(t *testing.T) {
+	document := `
+@prefix nba: <http://nba.example/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+PREFIX team: <http://nba.example/team/>
+@base <http://nba.example/people/> .
+
+# Pierce played for the Celtics
+<paul-pierce> a nba:Player ;
+	nba:name "Paul Pierce"@en, 'The Truth' ;
+	nba:playedFor team:Celtics ;
+	nba:seasons 19 ;
+	nba:ppg 19.7 ;
+	nba:retired true ;
+	nba:drafted "1998-06-24T00:00:00Z"^^xsd:dateTime ;
+	nba:bio """Long
+story""" ;
+.
+_:ref nba:rating "1.5E0"^^xsd:double ; nba:knows [ nba:name "anon" ] .
+`
+
+	edges := readAll(t, NewTurtleReader(strings.NewReader(document)).Read)
+
+	if len(edges) != 12 {
+		t.Fatalf("got %v edges: %v", len(edges), edges)
+	}
+
+	pierce := []byte("http://nba.example/people/paul-pierce")
+	want := []Edge{
+		NewEdge(pierce, []byte(rdfType), []byte("http://nba.example/Player")),
+		NewEdge(pierce, []byte("http://nba.example/name"), "Paul Pierce"),
+		NewEdge(pierce, []byte("http://nba.example/name"), "The Truth"),
+		NewEdge(pierce, []byte("http://nba.example/playedFor"), []byte("http://nba.example/team/Celtics")),
+		NewEdge(pierce, []byte("http://nba.example/seasons"), int64(19)),
+		NewEdge(pierce, []byte("http://nba.example/ppg"), 19.7),
+		NewEdge(pierce, []byte("http://nba.example/retired"), true),
+		NewEdge(pierce, []byte("http://nba.example/drafted"), time.Date(1998, time.June, 24, 0, 0, 0, 0, time.UTC)),
+		NewEdge(pierce, []byte("http://nba.example/bio"), "Long\nstory"),
+	}
+
+	if !reflect.DeepEqual(edges[:9], want) {
+		t.Errorf("got %v, want %v", edges[:9], want)
+	}
+
+	if label := string(edges[9].subject); !strings.HasPrefix(label, "_:ref_") || edges[9].object != 1.5 {
+		t.Errorf("got %v for the labelled blank node", edges[9])
+	}
+
+	// the anonymous node is named by both the edge that describes it and the one that refers to it
+	anonymous := edges[10].subject
+	if !isBlankNode(anonymous) || !bytes.Equal(edges[11].object.([]byte), anonymous) || edges[10].object != "anon" {
+		t.Errorf("got %v and %v for the anonymous node", edges[10], edges[11])
+	}
+}
+
+func TestRDFReaderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		turtle   bool
+		want     string
+	}{
+		{"it reports the line", "<a> <b> <c> .\n<a> <b> \"c .\n", false, "line 2: unterminated string"},
+		{"it rejects a missing full stop", "<a> <b> <c>\n", false, `line 2: expected '.', found the end of the input`},
+		{"it rejects prefixes in N-Triples", "@prefix a: <a> .\n", false, "line 1: expected an IRI or blank node, found '@'"},
+		{"it rejects undefined prefixes", "x:a x:b x:c .\n", true, `line 1: undefined prefix "x"`},
+		{"it rejects invalid numbers", `<a> <b> "x"^^<http://www.w3.org/2001/XMLSchema#integer> .`, true, "line 1: invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newRDFParser(strings.NewReader(tt.document), tt.turtle)
+
+			var e error
+			for e == nil {
+				_, e = parser.read()
+			}
+
+			var lineError *LineError
+			if !errors.As(e, &lineError) || !strings.HasPrefix(e.Error(), tt.want) {
+				t.Errorf("got %v, want %v", e, tt.want)
+			}
+		})
+	}
+}
+
+// failingReader reads its text, then fails
+type failingReader struct {
+	text string
+	err  error
+}
+
+func (fr *failingReader) Read(b []byte) (int, error) {
+	if fr.text == "" {
+		return 0, fr.err
+	}
+
+	n := copy(b, fr.text)
+	fr.text = fr.text[n:]
+	return n, nil
+}
+
+func TestRDFReaderReadErrors(t *testing.T) {
+	diskGone := errors.New("disk gone")
+
+	for _, turtle := range []bool{ false, true } {
+		parser := newRDFParser(&failingReader{ text: "<a> <b> <c> .\n<a> <b", err: diskGone }, turtle)
+
+		var edges []Edge
+		var e error
+		for e == nil {
+			var edge Edge
+			if edge, e = parser.read(); e == nil {
+				edges = append(edges, edge)
+			}
+		}
+
+		if len(edges) != 1 || e != diskGone {
+			t.Errorf("got %v and %v, want one edge and %v", edges, e, diskGone)
+		}
+	}
+
+	graph := NewSimpleGraph(NewMemoryGraph())
+	_, e := graph.ImportNTriples(context.Background(), &failingReader{ text: "<a> <b> <c> .\n", err: diskGone }, RDFOptions{})
+
+	if e != diskGone {
+		t.Errorf("got %v, want %v", e, diskGone)
+	}
+}
+
+func rdfGraph() *SimpleGraph {
+	graph := NewSimpleGraph(NewMemoryGraph())
+	pierce := []byte("http://nba.example/paul-pierce")
+
+	_ = graph.AddEdges([]Edge{
+		NewEdge(pierce, []byte(rdfType), []byte("http://nba.example/Player")),
+		NewEdge(pierce, []byte("http://nba.example/name"), "Paul \"The Truth\" Pierce\n"),
+		NewEdge(pierce, []byte("http://nba.example/playedFor"), []byte("http://nba.example/Celtics")),
+		NewEdge(pierce, []byte("http://nba.example/playedFor"), []byte("http://nba.example/Nets")),
+		NewEdge(pierce, []byte("http://nba.example/seasons"), int64(19)),
+		NewEdge(pierce, []byte("http://nba.example/ppg"), 19.7),
+		NewEdge(pierce, []byte("http://nba.example/retired"), true),
+		NewEdge(pierce, []byte("http://nba.example/drafted"), time.Date(1998, time.June, 24, 0, 0, 0, 0, time.UTC)),
+		NewEdge([]byte("_:b1"), []byte("http://nba.example/knows"), pierce),
+		NewEdge([]byte("Kevin Garnett"), []byte("former player"), []byte("Celtics")),
+	})
+
+	return graph
+}
+
+func TestSimpleGraph_NTriplesRoundTrip(t *testing.T) {
+	graph := rdfGraph()
+	var document bytes.Buffer
+
+	written, e := graph.ExportNTriples(context.Background(), &document, NewQuery())
+
+	if e != nil || written != 10 {
+		t.Fatalf("wrote %v edges: %v", written, e)
+	}
+
+	if line := `<Kevin\u0020Garnett> <former\u0020player> <Celtics> .`; !strings.Contains(document.String(), line) {
+		t.Errorf("%v doesn't contain %v", document.String(), line)
+	}
+
+	imported := NewSimpleGraph(NewMemoryGraph())
+	report, e := imported.ImportNTriples(context.Background(), &document, RDFOptions{})
+
+	if e != nil || report.Edges != 10 {
+		t.Fatalf("imported %+v: %v", report, e)
+	}
+
+	// the blank node comes back under a label of its own
+	got, want := queriedEdges(t, imported, NewQuery()), queriedEdges(t, graph, NewQuery())
+
+	if !reflect.DeepEqual(relabelBlankNodes(got), relabelBlankNodes(want)) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// relabelBlankNodes gives every blank node the same label, to compare graphs that differ only in their labels
+func relabelBlankNodes(edges []Edge) []Edge {
+	for i, edge := range edges {
+		if isBlankNode(edge.subject) {
+			edges[i].subject = []byte("_:blank")
+		}
+
+		if object, ok := edge.object.([]byte); ok && isBlankNode(object) {
+			edges[i].object = []byte("_:blank")
+		}
+	}
+
+	return edges
+}
+
+func TestSimpleGraph_ImportScopesBlankNodes(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+
+	for _, document := range []string{
+		"_:b0 <http://nba.example/name> \"Paul Pierce\" .\n",
+		"_:b0 <http://nba.example/name> \"Kevin Garnett\" .\n_:b0 <http://nba.example/team> _:b1 .\n",
+	} {
+		if _, e := graph.ImportNTriples(context.Background(), strings.NewReader(document), RDFOptions{}); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	names := queriedEdges(t, graph, NewQuery().WithPredicate([]byte("http://nba.example/name")))
+
+	if len(names) != 2 || bytes.Equal(names[0].subject, names[1].subject) {
+		t.Fatalf("expected each document's _:b0 to be a node of its own, got %v", names)
+	}
+
+	// within a document, a label always names the same node
+	garnett := names[0].subject
+	if names[0].object != "Kevin Garnett" {
+		garnett = names[1].subject
+	}
+
+	if teams := queriedEdges(t, graph, NewQuery().WithSubject(garnett).WithPredicate([]byte("http://nba.example/team"))); len(teams) != 1 {
+		t.Errorf("got %v for Garnett's team", teams)
+	}
+}
+
+func TestSimpleGraph_ExportTurtle(t *testing.T) {
+	graph := rdfGraph()
+	var document bytes.Buffer
+
+	query := NewQuery().WithSubject([]byte("http://nba.example/paul-pierce"))
+	_, e := graph.ExportTurtle(context.Background(), &document, query, map[string]string{
+		"nba": "http://nba.example/",
+		"xsd": xsdNamespace,
+	})
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	want := `@prefix nba: <http://nba.example/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+nba:paul-pierce nba:drafted "1998-06-24T00:00:00Z"^^xsd:dateTime ;
+	nba:name "Paul \"The Truth\" Pierce\n" ;
+	nba:playedFor nba:Celtics ,
+		nba:Nets ;
+	nba:ppg "1.97E+01"^^xsd:double ;
+	nba:retired true ;
+	nba:seasons 19 ;
+	a nba:Player .
+`
+
+	if document.String() != want {
+		t.Errorf("got\n%v\nwant\n%v", document.String(), want)
+	}
+
+	// and it reads back
+	imported := NewSimpleGraph(NewMemoryGraph())
+	if _, e := imported.ImportTurtle(context.Background(), &document, RDFOptions{}); e != nil {
+		t.Fatal(e)
+	}
+
+	if got, want := queriedEdges(t, imported, NewQuery()), queriedEdges(t, graph, query); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSimpleGraph_ImportRDFDryRun(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+	document := "<a> <b> <c> .\n<a> <b> <d> .\n<a> <b> \"e .\n<a> <b> <f> .\n"
+
+	report, e := graph.ImportNTriples(context.Background(), strings.NewReader(document), RDFOptions{ BatchSize: 1, DryRun: true })
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if report.Edges != 2 || len(report.Malformed) != 1 || report.Malformed[0].Line != 3 {
+		t.Errorf("got %+v", report)
+	}
+
+	if edges := queriedEdges(t, graph, NewQuery()); len(edges) != 0 {
+		t.Errorf("a dry run wrote %v", edges)
+	}
+
+	report, e = graph.ImportTurtle(context.Background(), strings.NewReader("<a> <b> <c>, <d> .\n"), RDFOptions{ DryRun: true })
+
+	if e != nil || report.Edges != 2 || len(report.Malformed) != 0 {
+		t.Errorf("got %+v, %v", report, e)
+	}
+
+	if edges := queriedEdges(t, graph, NewQuery()); len(edges) != 0 {
+		t.Errorf("a dry run wrote %v", edges)
+	}
+}
@@ -1,15 +1,123 @@
 package simplegraph
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"strings"
+	"sync"
 )
 
 type Query struct {
-	subject, predicate, object []byte
+	subject, predicate []byte
+	object interface{}
+	objectRange *ValueRange
+	limit, offset int
+	cursor Cursor
 	subjectVariable, predicateVariable, objectVariable string
 }
+
+// NewQuery returns an empty Query. Positions are constrained by chaining the With* methods, e.g.
+//
+//	NewQuery().WithSubjectVariable("player").WithPredicate([]byte("played for")).WithObject([]byte("Celtics"))
+func NewQuery() Query {
+	return Query{}
+}
+
+// WithSubject returns a copy of the query with the subject fixed to the given value
+func (query Query) WithSubject(subject []byte) Query {
+	query.subject = subject
+	return query
+}
+
+// WithPredicate returns a copy of the query with the predicate fixed to the given value
+func (query Query) WithPredicate(predicate []byte) Query {
+	query.predicate = predicate
+	return query
+}
+
+// WithObject returns a copy of the query with the object fixed to the given value, of any type an edge's object can
+// hold. It only matches objects of the same type.
+func (query Query) WithObject(object interface{}) Query {
+	query.object = object
+	return query
+}
+
+// WithObjectRange returns a copy of the query that only matches objects in the range, e.g.
+//
+//	NewQuery().WithSubjectVariable("player").WithPredicate([]byte("seasons played")).WithObjectRange(GreaterThan(int64(10)))
+//
+// It can be combined with WithObjectVariable, but not with WithObject.
+func (query Query) WithObjectRange(valueRange ValueRange) Query {
+	query.objectRange = &valueRange
+	return query
+}
+
+// WithSubjectVariable returns a copy of the query that binds the subject of each match to the named variable
+func (query Query) WithSubjectVariable(name string) Query {
+	query.subjectVariable = name
+	return query
+}
+
+// WithPredicateVariable returns a copy of the query that binds the predicate of each match to the named variable
+func (query Query) WithPredicateVariable(name string) Query {
+	query.predicateVariable = name
+	return query
+}
+
+// WithObjectVariable returns a copy of the query that binds the object of each match to the named variable
+func (query Query) WithObjectVariable(name string) Query {
+	query.objectVariable = name
+	return query
+}
+
+// validate checks that the query's object, if fixed, is of a type edges can hold
+func (query Query) validate() error {
+	if query.limit < 0 || query.offset < 0 {
+		return errors.New("a query's limit and offset can't be negative")
+	}
+
+	if query.objectRange != nil {
+		if query.object != nil {
+			return errors.New("a query can't both fix its object and constrain it to a range")
+		}
+
+		return query.objectRange.validate()
+	}
+
+	if query.object == nil {
+		return nil
+	}
+
+	return validateValue(query.object)
+}
+
+// String renders the pattern as e.g. (?x, "friend", "jess"), with _ for a position that's neither fixed nor a variable
+func (query Query) String() string {
+	position := func(value interface{}, variable string) string {
+		if value != nil {
+			return formatValue(value)
+		} else if variable != "" {
+			return "?" + variable
+		}
+
+		return "_"
+	}
+
+	object := position(query.object, query.objectVariable)
+	if query.objectRange != nil {
+		object = strings.TrimPrefix(fmt.Sprintf("%v %v", object, query.objectRange), "_ ")
+	}
+
+	return fmt.Sprintf("(%v, %v, %v)",
+		position(fieldValue(query.subject), query.subjectVariable),
+		position(fieldValue(query.predicate), query.predicateVariable),
+		object)
+}
 //
 //type Edge interface {
 //	Subject() []byte
@@ -27,15 +135,44 @@ func (e *EdgeResult) Edge() *Edge {
 }
 
 type Edge struct {
-	subject, predicate, object []byte
+	subject, predicate []byte
+	object interface{}
+}
+
+// NewEdge returns an edge from subject to object. The object can be any of the types listed in values.go; AddEdges
+// rejects anything else.
+func NewEdge(subject, predicate []byte, object interface{}) Edge {
+	return Edge{
+		subject:   subject,
+		predicate: predicate,
+		object:    object,
+	}
+}
+
+func (e Edge) Subject() []byte {
+	return e.subject
+}
+
+func (e Edge) Predicate() []byte {
+	return e.predicate
+}
+
+// Object returns the edge's object, as the Go type it was added with
+func (e Edge) Object() interface{} {
+	return e.object
 }
 
 func (e Edge) String() string {
-	return fmt.Sprintf("Edge[subject: %v, predicate: %v, object: %v]", string(e.subject), string(e.predicate), string(e.object))
+	object := fmt.Sprintf("%v", e.object)
+	if value, ok := e.object.([]byte); ok {
+		object = string(value)
+	}
+
+	return fmt.Sprintf("Edge[subject: %v, predicate: %v, object: %v]", string(e.subject), string(e.predicate), object)
 }
 
 func (e Edge) toBytes() []byte {
-	return tuple.Tuple{ e.subject, e.predicate, e.object }.Pack()
+	return tuple.Tuple{ e.subject, e.predicate, encodeValue(e.object) }.Pack()
 }
 
 func (e Edge) toComparisonBytes(comparisonOrdering []DataField) []byte {
@@ -48,7 +185,7 @@ func (e Edge) toComparisonBytes(comparisonOrdering []DataField) []byte {
 		case PREDICATE:
 			comparisonTuple[i] = e.predicate
 		case OBJECT:
-			comparisonTuple[i] = e.object
+			comparisonTuple[i] = encodeValue(e.object)
 		}
 
 	}
@@ -56,22 +193,12 @@ func (e Edge) toComparisonBytes(comparisonOrdering []DataField) []byte {
 	return comparisonTuple.Pack()
 }
 
-func fromBytes(bytes []byte) *Edge {
-	tuples, e := tuple.Unpack(bytes)
-
-	if e != nil {
-		panic(e)
-	}
-
-	return &Edge {
-		subject: tuples[0].([]byte),
-		predicate: tuples[1].([]byte),
-		object: tuples[2].([]byte),
-	}
-}
-
 type SimpleGraph struct {
 	kvstore KVStore
+	sortMemoryBudget int
+
+	statsMu sync.Mutex
+	stats *Statistics
 }
 
 func NewSimpleGraph(kvstore KVStore) *SimpleGraph {
@@ -80,7 +207,91 @@ func NewSimpleGraph(kvstore KVStore) *SimpleGraph {
 	}
 }
 
+// SetSortMemoryBudget caps the memory, in bytes, a query may use to sort intermediate results before spilling them to
+// temporary files. Zero uses the default of 64MiB.
+func (graph *SimpleGraph) SetSortMemoryBudget(bytes int) {
+	graph.sortMemoryBudget = bytes
+}
+
+// AddEdges writes each edge to all six indices in a single call to the store, so either every edge is added or none
+// are. FoundationDB caps a transaction at 10MB, so very large writes should use AddEdgesInBatches.
 func (graph *SimpleGraph) AddEdges(edges []Edge) error {
+	if e := validateEdges(edges); e != nil {
+		return e
+	}
+
+	return graph.kvstore.Put(indexKeys(edges) ...)
+}
+
+// DeleteEdges removes each edge from all six indices in a single call to the store, so no index is left pointing at
+// an edge the others have forgotten. Edges that aren't in the graph are ignored.
+func (graph *SimpleGraph) DeleteEdges(edges []Edge) error {
+	if e := validateEdges(edges); e != nil {
+		return e
+	}
+
+	return graph.kvstore.Delete(indexKeys(edges) ...)
+}
+
+func validateEdges(edges []Edge) error {
+	for _, edge := range edges {
+		if e := validateValue(edge.object); e != nil {
+			return fmt.Errorf("%v: %v", edge, e)
+		}
+	}
+
+	return nil
+}
+
+const deleteMatchingBatchSize = 500
+
+// DeleteMatching removes every edge matching the query, and returns how many were removed. Matches are read and
+// deleted a batch at a time, with the scan restarted after each batch, so no more than a batch is held in memory, no
+// store is written to while it holds a read open, and the scan never observes its own deletes. Each batch of edges is
+// removed from all six indices at once; if a batch fails, the edges in earlier batches stay deleted.
+func (graph *SimpleGraph) DeleteMatching(ctx context.Context, query Query) (int, error) {
+	deleted := 0
+	matches := make([]Edge, 0, deleteMatchingBatchSize)
+
+	for {
+		batch := query.WithLimit(deleteMatchingBatchSize)
+		if query.limit > 0 && query.limit - deleted < deleteMatchingBatchSize {
+			batch = query.WithLimit(query.limit - deleted)
+		}
+
+		stream, e := graph.GetEdges(ctx, batch)
+
+		if e != nil {
+			return deleted, e
+		}
+
+		matches = matches[:0]
+		for edge := range stream.Edges() {
+			matches = append(matches, *edge)
+		}
+
+		if e := stream.Err(); e != nil {
+			return deleted, e
+		}
+
+		if len(matches) == 0 {
+			return deleted, nil
+		}
+
+		if e := graph.DeleteEdges(matches); e != nil {
+			return deleted, e
+		}
+
+		deleted += len(matches)
+
+		// a short batch was the last of them
+		if len(matches) < batch.limit || deleted == query.limit {
+			return deleted, nil
+		}
+	}
+}
+
+func indexKeys(edges []Edge) [][]byte {
 	kvKeys := make([][]byte, len(edges)*len(Indices))
 
 	i := 0
@@ -91,87 +302,257 @@ func (graph *SimpleGraph) AddEdges(edges []Edge) error {
 		}
 	}
 
-	return graph.kvstore.Put(kvKeys ...)
+	return kvKeys
 }
 
-func (graph *SimpleGraph) GetEdges(query Query) (<-chan *Edge, error){
-	parsedQuery := transformQuery(query)
-	idx := findIndices(parsedQuery)
-	return graph._getRangeStreaming(query, idx[0])
+// GetEdges streams the edges matching the query, in the order of the index it reads. A query with a limit reads no
+// more of the index than it needs, and once its stream is drained, the stream's Cursor resumes after the last edge.
+func (graph *SimpleGraph) GetEdges(ctx context.Context, query Query) (*EdgeStream, error) {
+	idx, span, e := graph.planScan(query)
+
+	if e != nil {
+		return nil, e
+	}
+
+	page := query.page(idx)
+	span.limit = page.scanLimit()
+
+	ctx, cancel := context.WithCancel(ctx)
+	errs := &pipelineError{}
+
+	edges := page.edges(ctx, graph._getRangeStreaming(ctx, idx, span, errs, nil))
+	return newEdgeStream(ctx, cancel, edges, errs, page), nil
+}
+
+// planScan picks the index to answer a single pattern from, and the part of it to read
+func (graph *SimpleGraph) planScan(query Query) (*hexastoreIndex, keySpan, error) {
+	if e := query.validate(); e != nil {
+		return nil, keySpan{}, e
+	}
+
+	idx, _ := chooseIndex(query, nil)
+	span, e := idx.spanFor(query)
+
+	return idx, span, e
+}
+
+// keySpan is the part of an index a query reads: every key under prefix, or if begin is set, the keys from begin up
+// to end. A limit stops the read after that many keys.
+type keySpan struct {
+	prefix, begin, end []byte
+	limit              int
 }
 
-func (graph *SimpleGraph) _getRangeStreaming(query Query, idx *hexastoreIndex) (<-chan *Edge, error){
-	queryRange := idx.toRangeFromQuery(transformQuery(query))
+func (idx hexastoreIndex) spanFor(query Query) (keySpan, error) {
+	span := keySpan{ prefix: idx.toRangeFromQuery(transformQuery(query)) }
 
+	if query.objectRange != nil {
+		span.begin, span.end = query.objectRange.keyRange(span.prefix)
+	}
+
+	if query.cursor != "" {
+		begin, end := span.keyRange()
+		key, e := query.cursor.key()
+
+		if e != nil || bytes.Compare(key, begin) < 0 || bytes.Compare(key, end) >= 0 {
+			return keySpan{}, errors.New("the cursor doesn't belong to this query")
+		}
+
+		// the first key after the cursor
+		span.begin, span.end = append(key, 0x00), end
+	}
+
+	return span, nil
+}
+
+func (span keySpan) keyRange() (begin, end []byte) {
+	if span.begin != nil {
+		return span.begin, span.end
+	}
+
+	// index keys are tuples, so never end with 0xff and always have a successor
+	end, _ = fdb.Strinc(span.prefix)
+	return span.prefix, end
+}
+
+// Predicates lists the distinct predicates that start with prefix, in order, up to limit of them if it's more than 0.
+// It skips from one predicate to the next in the pso index, so reads one key per predicate rather than every edge.
+func (graph *SimpleGraph) Predicates(ctx context.Context, prefix []byte, limit int) ([][]byte, error) {
+	idx := Indices["pso"]
+
+	// a packed []byte ends with a terminator, without which it's a prefix of every longer one
+	begin := idx.ss.Pack(tuple.Tuple{ prefix })
+	begin = begin[:len(begin) - 1]
+	end, _ := fdb.Strinc(begin)
+
+	var predicates [][]byte
+
+	for limit <= 0 || len(predicates) < limit {
+		errs := &pipelineError{}
+		var predicate []byte
+
+		for edge := range graph._getRangeStreaming(ctx, idx, keySpan{ begin: begin, end: end, limit: 1 }, errs, nil) {
+			predicate = edge.predicate
+		}
+
+		if e := errs.get(); e != nil {
+			return nil, e
+		}
+
+		if predicate == nil {
+			break
+		}
+
+		predicates = append(predicates, predicate)
+
+		// the first key after every edge with this predicate
+		begin, _ = fdb.Strinc(idx.ss.Pack(tuple.Tuple{ predicate }))
+	}
+
+	return predicates, ctx.Err()
+}
+
+func (graph *SimpleGraph) _getRangeStreaming(ctx context.Context, idx *hexastoreIndex, span keySpan,
+	errs *pipelineError, metrics *OperatorMetrics) <-chan *Edge {
 	kvs := make(chan []byte)
+	kvError := make(chan error, 1)
 	edges := make(chan *Edge)
 
+	// the scan has its own context, so a key that can't be decoded stops it without cancelling the rest of the query
+	scanCtx, cancelScan := context.WithCancel(ctx)
+
 	go func(rawKVStream chan<- []byte) {
-		e := graph.kvstore.Get(queryRange, rawKVStream)
-		if e != nil {
-			panic(e)
+		if span.begin == nil && span.limit == 0 {
+			kvError <- graph.kvstore.Get(scanCtx, span.prefix, rawKVStream)
+		} else {
+			begin, end := span.keyRange()
+			kvError <- scan(scanCtx, graph.kvstore, begin, end, ScanOptions{ Limit: span.limit }, rawKVStream)
 		}
 	}(kvs)
 
 	go func(rawKVStream <-chan []byte, edgeOutput chan<- *Edge) {
 		defer close(edgeOutput)
+		defer cancelScan()
 
+		DECODE:
 		for rawKey := range rawKVStream {
+			metrics.scanned(rawKey)
 			edge, e := idx.fromBytes(rawKey)
 
 			if e != nil {
-				panic(e)
+				errs.set(e)
+
+				// stop the store, then drain the few keys it may already be sending so it can close the raw stream
+				cancelScan()
+				for range rawKVStream {
+				}
+
+				break
+			}
+
+			select {
+			case edgeOutput <- edge:
+			case <-ctx.Done():
+				// the store is watching the same context, so it will stop sending and close the raw stream
+				break DECODE
 			}
+		}
 
-			edgeOutput <- edge
+		// the store closes the raw stream before it returns, so wait for its result before closing our output
+		if e := <-kvError; e != nil {
+			errs.set(e)
 		}
 	}(kvs, edges)
 
-	return edges, nil
+	return edges
 }
 
-func (graph *SimpleGraph) GetRangeStreamingAnd(query1 Query, query2 Query) (<-chan *Edge, error){
+func (graph *SimpleGraph) GetRangeStreamingAnd(ctx context.Context, query1 Query, query2 Query) (*EdgeStream, error) {
+	for _, query := range []Query{ query1, query2 } {
+		if e := query.validate(); e != nil {
+			return nil, e
+		}
+
+		if query.objectRange != nil || query.isPaged() {
+			return nil, errors.New("GetRangeStreamingAnd doesn't support ranges or paging; use Match or GetEdges")
+		}
+	}
+
 	idx1, idx2 := findIndexPair(transformQuery(query1), transformQuery(query2))
+	span1, _ := idx1.spanFor(query1)
+	ctx, cancel := context.WithCancel(ctx)
+	errs := &pipelineError{}
 
 	// EZ: Can answer with the most specific index, which has already been selected
 	if idx1 == idx2 {
-		fmt.Printf("Same index: %v\n", idx1)
-		return graph._getRangeStreaming(query1, idx1)
+		return newEdgeStream(ctx, cancel, graph._getRangeStreaming(ctx, idx1, span1, errs, nil), errs, nil), nil
+	}
+
+	span2, _ := idx2.spanFor(query2)
+	stream1 := graph._getRangeStreaming(ctx, idx1, span1, errs, nil)
+	stream2 := graph._getRangeStreaming(ctx, idx2, span2, errs, nil)
+
+	join := OrderedStreamJoin{
+		tripleOrder: TripleOrder{ dataFieldOrder: []DataField{idx1.ordering[1], idx1.ordering[2] } },
 	}
 
-	fmt.Printf("Choosing index: %v then %v\n", idx1, idx2)
+	return newEdgeStream(ctx, cancel, join.join(ctx, stream1, stream2), errs, nil), nil
+}
 
-	stream1, e := graph._getRangeStreaming(query1, idx1)
+// Search streams a binding of the query's variables for each matching edge. Like GetEdges, it can be limited and
+// paged through with cursors.
+func (graph *SimpleGraph) Search(ctx context.Context, query Query) (*SearchStream, error) {
+	idx, span, e := graph.planScan(query)
 
 	if e != nil {
 		return nil, e
 	}
 
-	stream2, e := graph._getRangeStreaming(query2, idx2)
+	page := query.page(idx)
+	variables := query.toVariableMap()
 
-	if e != nil {
-		return nil, e
+	// a pattern like (?x, knows, ?x) skips edges whose fields disagree, so the scan can't know when to stop
+	if len(variables) == len(variableNames(query)) {
+		span.limit = page.scanLimit()
 	}
 
-	join := OrderedStreamJoin{
-		tripleOrder: TripleOrder{ dataFieldOrder: []DataField{idx1.ordering[1], idx1.ordering[2] } },
+	ctx, cancel := context.WithCancel(ctx)
+	errs := &pipelineError{}
+
+	stream := VariableStream{
+		variables: variables,
 	}
 
-	return join.join(stream1, stream2), nil
+	results := page.results(ctx, stream.join(ctx, graph._getRangeStreaming(ctx, idx, span, errs, nil)))
+	return newSearchStream(ctx, cancel, results, errs, page), nil
 }
 
-func (graph *SimpleGraph) Search(query Query) (<-chan *SearchResults, error){
-	edges, e := graph.GetEdges(query)
+// Match finds every way to bind the patterns' variables such that all of the patterns match an edge, e.g. the
+// friends of friends connecting paul to jess:
+//
+//	graph.Match(ctx,
+//		NewQuery().WithSubject([]byte("paul")).WithPredicate([]byte("friend")).WithObjectVariable("x"),
+//		NewQuery().WithSubjectVariable("x").WithPredicate([]byte("friend")).WithObjectVariable("y"),
+//		NewQuery().WithSubjectVariable("y").WithPredicate([]byte("friend")).WithObject([]byte("jess")))
+//
+// A variable used in more than one pattern must be bound to the same value in each. The patterns are joined in the
+// order the planner estimates is cheapest, which is better informed once the graph has been analyzed with Analyze, or
+// its saved statistics read with LoadStatistics.
+func (graph *SimpleGraph) Match(ctx context.Context, patterns ...Query) (*SearchStream, error) {
+	plan, e := graph.costModel().generateQueryPlan(patterns...)
 
 	if e != nil {
 		return nil, e
 	}
 
-	stream := VariableStream{
-		variables: query.toVariableMap(),
-	}
+	return graph.execute(ctx, plan), nil
+}
 
-	return stream.join(edges), nil
+func (graph *SimpleGraph) execute(ctx context.Context, plan *queryPlan) *SearchStream {
+	ctx, cancel := context.WithCancel(ctx)
+	errs := &pipelineError{}
+
+	return newSearchStream(ctx, cancel, plan.source.execute(ctx, graph, errs), errs, nil)
 }
 
 func (query *Query) toVariableMap() map[DataField]string {
@@ -224,7 +605,7 @@ func (idx hexastoreIndex) toBytes(edge *Edge) []byte {
 		case PREDICATE:
 			indexTuple[i] = edge.predicate
 		case OBJECT:
-			indexTuple[i] = edge.object
+			indexTuple[i] = encodeValue(edge.object)
 		default:
 			panic(fmt.Sprintf("Unknown element type: %v", et))
 		}
@@ -240,42 +621,76 @@ func (idx hexastoreIndex) fromBytes(kvBytes []byte) (*Edge, error) {
 		return nil, e
 	}
 
+	if len(unpacked) != len(idx.ordering) + 1 {
+		return nil, fmt.Errorf("malformed index key %x: expected %v tuple elements, found %v",
+			kvBytes, len(idx.ordering) + 1, len(unpacked))
+	}
+
 	edge := Edge{}
 
 	for i, dataField := range idx.ordering {
-		switch dataField {
 		// add 1 to tuple index to account for index subspace entry
+		element := unpacked[i + 1]
+
+		if dataField == OBJECT {
+			value, e := decodeValue(element)
+
+			if e != nil {
+				return nil, fmt.Errorf("malformed index key %x: %v", kvBytes, e)
+			}
+
+			edge.object = value
+			continue
+		}
+
+		value, ok := element.([]byte)
+
+		if !ok {
+			return nil, fmt.Errorf("malformed index key %x: element %v is %T, not []byte", kvBytes, i + 1, element)
+		}
+
+		switch dataField {
 		case SUBJECT:
-			edge.subject = unpacked[i + 1].([]byte)
+			edge.subject = value
 		case PREDICATE:
-			edge.predicate = unpacked[i + 1].([]byte)
-		case OBJECT:
-			edge.object = unpacked[i + 1].([]byte)
+			edge.predicate = value
 		}
 	}
 
 	return &edge, nil
 }
 
-func (idx hexastoreIndex) toRangeFromQuery(query map[DataField][]byte) []byte {
+// name spells out the index's ordering, e.g. "pso"
+func (idx hexastoreIndex) name() string {
+	letters := map[DataField]string{ SUBJECT: "s", PREDICATE: "p", OBJECT: "o" }
+	name := ""
+
+	for _, dataField := range idx.ordering {
+		name += letters[dataField]
+	}
+
+	return name
+}
+
+func (idx hexastoreIndex) toRangeFromQuery(query map[DataField]interface{}) []byte {
 	indexTuple := make(tuple.Tuple, 0)
 
 	for _, dataField := range idx.ordering {
 		if v := query[dataField]; v == nil {
 			break
 		} else {
-			indexTuple = append(indexTuple, v)
+			indexTuple = append(indexTuple, encodeValue(v))
 		}
 	}
 
 	return idx.ss.Pack(indexTuple)
 }
 
-func (idx hexastoreIndex) matchDepth(query map[DataField][]byte) int {
+func (idx hexastoreIndex) matchDepth(query map[DataField]interface{}) int {
 	depthOfMatch := 0
 
 	for _, dataField := range idx.ordering {
-		if len(query[dataField]) > 0 {
+		if query[dataField] != nil {
 			depthOfMatch++
 		} else {
 			break
@@ -284,5 +699,3 @@ func (idx hexastoreIndex) matchDepth(query map[DataField][]byte) int {
 
 	return depthOfMatch
 }
-
-func (idx hexastoreIndex) locationOf()
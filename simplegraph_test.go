@@ -1,8 +1,15 @@
 package simplegraph
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
@@ -155,18 +162,24 @@ func Test_findIndex(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := findIndices(transformQuery(tt.args.query))
+			got, _ := findIndices(transformQuery(tt.args.query))
 
-			anySuccess := false
+			allValid := len(got) == len(tt.want)
 
-			for _, validIndex := range tt.want {
-				if reflect.DeepEqual(got, validIndex) {
-					anySuccess = true
-					break
+			for _, index := range got {
+				anySuccess := false
+
+				for _, validIndex := range tt.want {
+					if reflect.DeepEqual(index, validIndex) {
+						anySuccess = true
+						break
+					}
 				}
+
+				allValid = allValid && anySuccess
 			}
 
-			if !anySuccess {
+			if !allValid {
 				t.Errorf("findIndices() = %v, want %v", got, tt.want)
 			}
 		})
@@ -230,3 +243,610 @@ func Test_hexastoreIndex_fromBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestNewEdge(t *testing.T) {
+	edge := NewEdge([]byte("S"), []byte("P"), []byte("O"))
+
+	if !reflect.DeepEqual(edge, Edge{subject: []byte("S"), predicate: []byte("P"), object: []byte("O")}) {
+		t.Errorf("NewEdge() = %v", edge)
+	}
+
+	if string(edge.Subject()) != "S" || string(edge.Predicate()) != "P" || !reflect.DeepEqual(edge.Object(), []byte("O")) {
+		t.Errorf("edge accessors returned %s %s %s", edge.Subject(), edge.Predicate(), edge.Object())
+	}
+}
+
+func TestNewQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Query
+		want Query
+	}{
+		{
+			name: "it sets constants for each position",
+			got:  NewQuery().WithSubject([]byte("S")).WithPredicate([]byte("P")).WithObject([]byte("O")),
+			want: Query{subject: []byte("S"), predicate: []byte("P"), object: []byte("O")},
+		},
+		{
+			name: "it sets variables for each position",
+			got:  NewQuery().WithSubjectVariable("s").WithPredicateVariable("p").WithObjectVariable("o"),
+			want: Query{subjectVariable: "s", predicateVariable: "p", objectVariable: "o"},
+		},
+		{
+			name: "it mixes constants and variables",
+			got:  NewQuery().WithSubjectVariable("player").WithPredicate([]byte("played for")),
+			want: Query{subjectVariable: "player", predicate: []byte("played for")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("NewQuery() = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+// testGetRangeStreamingAnd runs the GetRangeStreamingAnd cases against any KVStore implementation
+func testGetRangeStreamingAnd(t *testing.T, kvstore KVStore) {
+	simpleGraph := NewSimpleGraph(kvstore)
+
+	_ = simpleGraph.AddEdges([]Edge{
+		{subject: []byte("Paul Pierce"), predicate: []byte("played for"), object: []byte("Celtics"),},
+		{subject: []byte("Al Jefferson"), predicate: []byte("played for"), object: []byte("Celtics"),},
+		{subject: []byte("Al Jefferson"), predicate: []byte("played for"), object: []byte("Timberwolves"),},
+		{subject: []byte("Kevin Garnett"), predicate: []byte("played for"), object: []byte("Celtics"),},
+		{subject: []byte("Kevin Garnett"), predicate: []byte("played for"), object: []byte("Timberwolves"),},
+		{subject: []byte("Kyrie Irving"), predicate: []byte("played for"), object: []byte("Cleveland"),},
+		{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
+		{subject: []byte("Smush Parker"), predicate: []byte("played for"), object: []byte("Lakers"),},
+		{subject: []byte("Sasha Vujačić"), predicate: []byte("played for"), object: []byte("Lakers"),},
+	})
+
+	type args struct {
+		queries []Query
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want []*Edge
+	}{
+		{ "filters a single subject",
+			args{ []Query { { subject: []byte("Kyrie Irving") }, { subject: []byte("Kyrie Irving") } } },
+			[]*Edge {
+				{subject: []byte("Kyrie Irving"), predicate: []byte("played for"), object: []byte("Cleveland"),},
+				{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
+			},
+		},
+		{ "filters two apiece",
+			args{ []Query {
+				{ subject: []byte("Kyrie Irving"), predicate: []byte("plays for")},
+				{ subject: []byte("Kyrie Irving"), object: []byte("Celtics") } } },
+			[]*Edge {
+				{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
+			},
+		},
+		{ "filters player and team in separate queries",
+			args{ []Query { { subject: []byte("Kyrie Irving") }, { object: []byte("Celtics") } } },
+			[]*Edge {
+				{subject: []byte("Kyrie Irving"), predicate: []byte("plays for"), object: []byte("Celtics"),},
+			},
+		},
+		//{ "filters",
+		//	// TODO: Mark this as an invalid query
+		//	args{ []Query { { object: []byte("Lakers") }, { object: []byte("Celtics") } } },
+		//	[]*Edge {},
+		//},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := simpleGraph.GetRangeStreamingAnd(context.Background(), tt.args.queries[0], tt.args.queries[1])
+
+			var edgeResults []*Edge
+			for edge := range got.Edges() {
+				edgeResults = append(edgeResults, edge)
+			}
+
+			if e := got.Err(); e != nil {
+				t.Fatalf("simpleGraph.GetRangeStreamingAnd() error = %v", e)
+			}
+
+			if !reflect.DeepEqual(edgeResults, tt.want) {
+				t.Errorf("simpleGraph.GetRangeStreamingAnd() = %v, want %v", edgeResults, tt.want)
+			}
+		})
+	}
+}
+
+
+// failingStore streams its keys and then fails, the way a store would on a mid-scan transaction error
+type failingStore struct {
+	keys [][]byte
+}
+
+func (f *failingStore) Get(ctx context.Context, prefix []byte, stream chan<- []byte) error {
+	defer close(stream)
+
+	for _, key := range f.keys {
+		stream <- key
+	}
+
+	return errors.New("store unavailable")
+}
+
+func (f *failingStore) GetRange(ctx context.Context, begin, end []byte, stream chan<- []byte) error {
+	return f.Get(ctx, begin, stream)
+}
+
+func (f *failingStore) Put(keys ...[]byte) error {
+	return nil
+}
+
+func (f *failingStore) Delete(keys ...[]byte) error {
+	return nil
+}
+
+func TestSimpleGraph_StreamErrors(t *testing.T) {
+	edge := NewEdge([]byte("S"), []byte("P"), []byte("O"))
+
+	var validKeys [][]byte
+	for _, index := range []string{"spo", "sop"} {
+		validKeys = append(validKeys, Indices[index].toBytes(&edge))
+	}
+
+	tests := []struct {
+		name    string
+		keys    [][]byte
+		wantErr string
+	}{
+		{"it reports store errors", validKeys, "store unavailable"},
+		{"it reports keys that aren't tuples", [][]byte{{0xff, 0xff}}, "unknown typecode"},
+		{"it reports keys with non-byte elements", [][]byte{subspace.Sub("spo").Pack(tuple.Tuple{int64(1), []byte("P"), []byte("O")})}, "not []byte"},
+		{"it reports keys with missing elements", [][]byte{subspace.Sub("spo").Pack(tuple.Tuple{[]byte("S")})}, "expected 4 tuple elements"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := NewSimpleGraph(&failingStore{keys: tt.keys})
+
+			edges, e := graph.GetEdges(context.Background(), NewQuery().WithSubject([]byte("S")))
+
+			if e != nil {
+				t.Fatalf("GetEdges() error = %v", e)
+			}
+
+			for range edges.Edges() {
+			}
+
+			if e := edges.Err(); e == nil || !strings.Contains(e.Error(), tt.wantErr) {
+				t.Errorf("GetEdges().Err() = %v, want %v", e, tt.wantErr)
+			}
+
+			results, _ := graph.Search(context.Background(), NewQuery().WithSubject([]byte("S")).WithObjectVariable("o"))
+
+			for range results.Results() {
+			}
+
+			if e := results.Err(); e == nil || !strings.Contains(e.Error(), tt.wantErr) {
+				t.Errorf("Search().Err() = %v, want %v", e, tt.wantErr)
+			}
+		})
+	}
+}
+
+// endlessStore sends a corrupt key, then valid keys until its context is cancelled, counting how many it sent
+type endlessStore struct {
+	sent int
+}
+
+func (es *endlessStore) Get(ctx context.Context, prefix []byte, stream chan<- []byte) error {
+	defer close(stream)
+
+	edge := NewEdge([]byte("S"), []byte("P"), []byte("O"))
+	key := []byte{ 0xff, 0xff }
+
+	for ; es.sent < 1000000; es.sent++ {
+		select {
+		case stream <- key:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		key = Indices["spo"].toBytes(&edge)
+	}
+
+	return nil
+}
+
+func (es *endlessStore) GetRange(ctx context.Context, begin, end []byte, stream chan<- []byte) error {
+	return es.Get(ctx, begin, stream)
+}
+
+func (es *endlessStore) Put(keys ...[]byte) error {
+	return nil
+}
+
+func (es *endlessStore) Delete(keys ...[]byte) error {
+	return nil
+}
+
+func TestSimpleGraph_DecodeErrorStopsScan(t *testing.T) {
+	store := &endlessStore{}
+	graph := NewSimpleGraph(store)
+
+	edges, e := graph.GetEdges(context.Background(), NewQuery().WithSubject([]byte("S")))
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for range edges.Edges() {
+	}
+
+	if e := edges.Err(); e == nil || !strings.Contains(e.Error(), "unknown typecode") {
+		t.Errorf("GetEdges().Err() = %v, want the decode error", e)
+	}
+
+	if store.sent > 10 {
+		t.Errorf("the store sent %v keys after the corrupt one", store.sent)
+	}
+}
+
+// waitForGoroutines waits for goroutines started by a test to exit, failing if they haven't after a second
+func waitForGoroutines(t *testing.T, baseline int) {
+	deadline := time.Now().Add(time.Second)
+
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			t.Fatalf("%v goroutines still running, expected %v", runtime.NumGoroutine(), baseline)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSimpleGraph_Cancellation(t *testing.T) {
+	simpleGraph := NewSimpleGraph(NewMemoryGraph())
+
+	var edges []Edge
+	for i := 0; i < 1000; i++ {
+		edges = append(edges, NewEdge([]byte("S"), []byte("P"), []byte(fmt.Sprintf("O%04d", i))))
+	}
+	edges = append(edges, NewEdge([]byte("T"), []byte("P"), []byte("O0500")))
+
+	_ = simpleGraph.AddEdges(edges)
+
+	t.Run("it releases the pipeline when the consumer closes early", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+
+		stream, _ := simpleGraph.Search(context.Background(), NewQuery().WithSubject([]byte("S")).WithObjectVariable("o"))
+		<-stream.Results()
+		stream.Close()
+
+		for range stream.Results() {
+		}
+
+		if e := stream.Err(); e != context.Canceled {
+			t.Errorf("Err() = %v, want %v", e, context.Canceled)
+		}
+
+		waitForGoroutines(t, baseline)
+	})
+
+	t.Run("it stops at the context deadline", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		stream, _ := simpleGraph.GetEdges(ctx, NewQuery().WithSubject([]byte("S")))
+		time.Sleep(10 * time.Millisecond)
+
+		for range stream.Edges() {
+		}
+
+		if e := stream.Err(); e != context.DeadlineExceeded {
+			t.Errorf("Err() = %v, want %v", e, context.DeadlineExceeded)
+		}
+
+		waitForGoroutines(t, baseline)
+	})
+
+	t.Run("it releases the unfinished side of a join", func(t *testing.T) {
+		baseline := runtime.NumGoroutine()
+
+		stream, _ := simpleGraph.GetRangeStreamingAnd(context.Background(),
+			NewQuery().WithSubject([]byte("T")),
+			NewQuery().WithObject([]byte("O0500")))
+
+		count := 0
+		for range stream.Edges() {
+			count++
+		}
+
+		if e := stream.Err(); e != nil || count != 1 {
+			t.Errorf("got %v edges, Err() = %v", count, e)
+		}
+
+		waitForGoroutines(t, baseline)
+	})
+}
+
+// indexContents returns every edge stored in each index, keyed by index name
+func indexContents(t *testing.T, kvstore KVStore) map[string][]string {
+	contents := make(map[string][]string)
+
+	for name, index := range Indices {
+		stream := make(chan []byte)
+
+		go func() {
+			_ = kvstore.Get(context.Background(), index.ss.Bytes(), stream)
+		}()
+
+		for key := range stream {
+			edge, e := index.fromBytes(key)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			contents[name] = append(contents[name], edge.String())
+		}
+
+		sort.Strings(contents[name])
+	}
+
+	return contents
+}
+
+func TestSimpleGraph_DeleteEdges(t *testing.T) {
+	pierce := NewEdge([]byte("Paul Pierce"), []byte("played for"), []byte("Celtics"))
+	garnett := NewEdge([]byte("Kevin Garnett"), []byte("played for"), []byte("Celtics"))
+	kyrie := NewEdge([]byte("Kyrie Irving"), []byte("plays for"), []byte("Celtics"))
+
+	playedFor := NewQuery().WithPredicate([]byte("played for"))
+	bird := NewQuery().WithSubject([]byte("Larry Bird"))
+
+	tests := []struct {
+		name string
+
+		// the test deletes the edges, or if it's set, the edges matching the pattern
+		edges       []Edge
+		pattern     *Query
+		wantDeleted int
+		want        []Edge
+	}{
+		{
+			name:  "it deletes edges from every index",
+			edges: []Edge{pierce, garnett},
+			want:  []Edge{kyrie},
+		},
+		{
+			name:  "it ignores edges that aren't in the graph",
+			edges: []Edge{NewEdge([]byte("S"), []byte("P"), []byte("O"))},
+			want:  []Edge{garnett, kyrie, pierce},
+		},
+		{
+			name:        "it deletes edges matching a pattern",
+			pattern:     &playedFor,
+			wantDeleted: 2,
+			want:        []Edge{kyrie},
+		},
+		{
+			name:        "it counts nothing when nothing matches",
+			pattern:     &bird,
+			wantDeleted: 0,
+			want:        []Edge{garnett, kyrie, pierce},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryGraph()
+			graph := NewSimpleGraph(store)
+
+			_ = graph.AddEdges([]Edge{pierce, garnett, kyrie})
+
+			if tt.pattern == nil {
+				if e := graph.DeleteEdges(tt.edges); e != nil {
+					t.Fatalf("DeleteEdges() error = %v", e)
+				}
+			} else {
+				deleted, e := graph.DeleteMatching(context.Background(), *tt.pattern)
+
+				if e != nil {
+					t.Fatalf("DeleteMatching() error = %v", e)
+				}
+
+				if deleted != tt.wantDeleted {
+					t.Errorf("DeleteMatching() deleted %v edges, want %v", deleted, tt.wantDeleted)
+				}
+			}
+
+			var want []string
+			for _, edge := range tt.want {
+				want = append(want, edge.String())
+			}
+
+			contents := indexContents(t, store)
+
+			for name := range Indices {
+				if got := contents[name]; !reflect.DeepEqual(got, want) {
+					t.Errorf("index %v = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// deleteRecordingStore counts the keys in each call to Delete
+type deleteRecordingStore struct {
+	*MemoryGraph
+	deletes []int
+}
+
+func (ds *deleteRecordingStore) Delete(keys ...[]byte) error {
+	ds.deletes = append(ds.deletes, len(keys))
+	return ds.MemoryGraph.Delete(keys...)
+}
+
+func TestSimpleGraph_DeleteMatchingBatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       Query
+		wantDeleted int
+		wantDeletes []int
+	}{
+		{"it deletes every match a batch at a time", NewQuery().WithPredicate([]byte("P")), 1200, []int{ 3000, 3000, 1200 }},
+		{"it stops at the limit", NewQuery().WithPredicate([]byte("P")).WithLimit(700), 700, []int{ 3000, 1200 }},
+		{"it keeps the offset", NewQuery().WithPredicate([]byte("P")).WithOffset(1100), 100, []int{ 600 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &deleteRecordingStore{ MemoryGraph: NewMemoryGraph() }
+			graph := NewSimpleGraph(store)
+
+			var edges []Edge
+			for i := 0; i < 1200; i++ {
+				edges = append(edges, NewEdge([]byte(fmt.Sprintf("S%04d", i)), []byte("P"), []byte("O")))
+			}
+
+			_ = graph.AddEdges(edges)
+			_ = graph.AddEdges([]Edge{ NewEdge([]byte("S"), []byte("Q"), []byte("O")) })
+
+			deleted, e := graph.DeleteMatching(context.Background(), tt.query)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if deleted != tt.wantDeleted || !reflect.DeepEqual(store.deletes, tt.wantDeletes) {
+				t.Errorf("deleted %v edges in %v, want %v in %v", deleted, store.deletes, tt.wantDeleted, tt.wantDeletes)
+			}
+
+			if left := len(queriedEdges(t, graph, NewQuery())); left != 1201 - tt.wantDeleted {
+				t.Errorf("left %v edges, want %v", left, 1201 - tt.wantDeleted)
+			}
+		})
+	}
+}
+
+// testStoreGetRange checks a store's range scans, which end before the end key
+func testStoreGetRange(t *testing.T, store KVStore) {
+	_ = store.Put([]byte("a/1"), []byte("b/1"), []byte("b/2"), []byte("b/3"), []byte("c/1"))
+
+	tests := []struct {
+		name       string
+		begin, end []byte
+		want       [][]byte
+	}{
+		{"it includes begin and excludes end", []byte("b/1"), []byte("b/3"), [][]byte{[]byte("b/1"), []byte("b/2")}},
+		{"it starts between keys", []byte("a/2"), []byte("b/2"), [][]byte{[]byte("b/1")}},
+		{"it crosses prefixes", []byte("b/3"), []byte("d"), [][]byte{[]byte("b/3"), []byte("c/1")}},
+		{"it returns nothing for an empty range", []byte("b/2"), []byte("b/2"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := make(chan []byte)
+			errs := make(chan error, 1)
+
+			go func() {
+				errs <- store.GetRange(context.Background(), tt.begin, tt.end, stream)
+			}()
+
+			var got [][]byte
+			for key := range stream {
+				got = append(got, key)
+			}
+
+			if e := <-errs; e != nil {
+				t.Fatalf("GetRange() error = %v", e)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRange() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// testStoreScan checks limited and reverse scans, whether the store implements RangeScanner or not
+func testStoreScan(t *testing.T, store KVStore) {
+	_ = store.Put([]byte("a/1"), []byte("b/1"), []byte("b/2"), []byte("b/3"), []byte("c/1"))
+
+	tests := []struct {
+		name       string
+		begin, end []byte
+		options    ScanOptions
+		want       [][]byte
+	}{
+		{"it limits a scan", []byte("b/"), []byte("c"), ScanOptions{Limit: 2}, [][]byte{[]byte("b/1"), []byte("b/2")}},
+		{"it scans in reverse, excluding end", []byte("a/1"), []byte("b/3"), ScanOptions{Reverse: true},
+			[][]byte{[]byte("b/2"), []byte("b/1"), []byte("a/1")}},
+		{"it reverses from past the last key", []byte("b/"), []byte("z"), ScanOptions{Reverse: true},
+			[][]byte{[]byte("c/1"), []byte("b/3"), []byte("b/2"), []byte("b/1")}},
+		{"it limits a reverse scan to the last keys", []byte("b/"), []byte("c"), ScanOptions{Limit: 2, Reverse: true},
+			[][]byte{[]byte("b/3"), []byte("b/2")}},
+		{"it ignores a limit past the end of the range", []byte("b/"), []byte("c"), ScanOptions{Limit: 10},
+			[][]byte{[]byte("b/1"), []byte("b/2"), []byte("b/3")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := make(chan []byte)
+			errs := make(chan error, 1)
+
+			go func() {
+				errs <- scan(context.Background(), store, tt.begin, tt.end, tt.options, stream)
+			}()
+
+			var got [][]byte
+			for key := range stream {
+				got = append(got, key)
+			}
+
+			if e := <-errs; e != nil {
+				t.Fatalf("scan() error = %v", e)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scan() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleGraph_Predicates(t *testing.T) {
+	graph := nbaGraph(t)
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{ name: "all", want: []string{ "coach", "former coach", "former player", "player" } },
+		{ name: "prefix", prefix: "former", want: []string{ "former coach", "former player" } },
+		{ name: "limit", limit: 3, want: []string{ "coach", "former coach", "former player" } },
+		{ name: "whole predicate", prefix: "player", want: []string{ "player" } },
+		{ name: "none", prefix: "referee" },
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predicates, e := graph.Predicates(context.Background(), []byte(tt.prefix), tt.limit)
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			var got []string
+			for _, predicate := range predicates {
+				got = append(got, string(predicate))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Predicates() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
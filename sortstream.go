@@ -1,46 +1,416 @@
 package simplegraph
 
 import (
+	"bufio"
 	"bytes"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"os"
 	"sort"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 )
 
+const defaultSortMemoryBudget = 64 << 20
+
+// maxSortFanIn is the most spilled runs merged at once, which bounds the files a sort holds open
+var maxSortFanIn = 64
+
+// SortStream sorts its input by tripleOrder. Results are buffered in memory until their estimated size passes
+// memoryBudget bytes, at which point the buffer is sorted and spilled to a temporary file as a run. Once the input is
+// exhausted, the runs are merged back together, so only one result per run is held in memory while emitting. Past
+// maxSortFanIn runs, the oldest are first merged into bigger runs, in as many passes as it takes.
 type SortStream struct {
 	variables map[DataField]string
 	tripleOrder TripleOrder
+	memoryBudget int
+	metrics *OperatorMetrics
 }
 
-func (ss *SortStream) join(input <-chan *SearchResults) <-chan *SearchResults {
+func (ss *SortStream) join(ctx context.Context, input <-chan *SearchResults, errs *pipelineError) <-chan *SearchResults {
 	output := make(chan *SearchResults)
 
 	go func(output chan<- *SearchResults) {
 		defer close(output)
-		buf := make([]*SearchResults, 0)
 
-		for edge := range input {
-			buf = append(buf, edge)
+		memoryBudget := ss.memoryBudget
+		if memoryBudget <= 0 {
+			memoryBudget = defaultSortMemoryBudget
+		}
+
+		var runs []*sortRun
+		defer func() {
+			for _, run := range runs {
+				run.close()
+			}
+		}()
+
+		buf := make([]sortEntry, 0)
+		bufferedBytes := 0
+
+		for result := range input {
+			entry := sortEntry{ key: ss.tripleOrder.fromVariables(result.variables), result: result }
+			buf = append(buf, entry)
+			bufferedBytes += entry.size()
+			ss.metrics.buffered(len(buf), bufferedBytes)
+
+			if bufferedBytes > memoryBudget {
+				sort.Sort(sortResults(buf))
+				run, e := spillRun(buf)
+
+				if run != nil {
+					runs = append(runs, run)
+				}
+
+				if e != nil {
+					errs.set(e)
+					return
+				}
+
+				buf = buf[:0]
+				bufferedBytes = 0
+			}
 		}
 
 		sort.Sort(sortResults(buf))
 
-		for _, result := range buf {
-			output <- result
+		if len(runs) == 0 {
+			for _, entry := range buf {
+				select {
+				case output <- entry.result:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			return
+		}
+
+		for len(runs) > maxSortFanIn {
+			if ctx.Err() != nil {
+				return
+			}
+
+			merged, e := newSpilledRun()
+
+			if e != nil {
+				errs.set(e)
+				return
+			}
+
+			if e = mergeRuns(runs[:maxSortFanIn], merged.write); e == nil {
+				e = merged.finish()
+			}
+
+			for _, run := range runs[:maxSortFanIn] {
+				run.close()
+			}
+
+			runs = append(runs[maxSortFanIn:], merged)
+
+			if e != nil {
+				errs.set(e)
+				return
+			}
+		}
+
+		// the last run never needs to touch the disk
+		runs = append(runs, &sortRun{ buffered: buf })
+
+		e := mergeRuns(runs, func(entry *sortEntry) error {
+			select {
+			case output <- entry.result:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+
+		if e != nil && ctx.Err() == nil {
+			errs.set(e)
 		}
 	}(output)
 
 	return output
 }
 
-type sortResults []*SearchResults
+// mergeRuns opens the runs and passes their entries to emit in order. The runs are left for the caller to close.
+func mergeRuns(runs []*sortRun, emit func(entry *sortEntry) error) error {
+	merge := make(runHeap, 0, len(runs))
+
+	for _, run := range runs {
+		if e := run.open(); e != nil {
+			return e
+		}
+
+		if e := run.advance(); e != nil {
+			return e
+		}
+
+		if run.current != nil {
+			merge = append(merge, run)
+		}
+	}
+
+	heap.Init(&merge)
+
+	for len(merge) > 0 {
+		run := merge[0]
+
+		if e := emit(run.current); e != nil {
+			return e
+		}
+
+		if e := run.advance(); e != nil {
+			return e
+		}
+
+		if run.current == nil {
+			heap.Pop(&merge)
+		} else {
+			heap.Fix(&merge, 0)
+		}
+	}
+
+	return nil
+}
+
+// sortEntry caches a result's comparison key, so it's packed once rather than on every comparison
+type sortEntry struct {
+	key    []byte
+	result *SearchResults
+}
+
+// size roughly estimates the memory an entry holds, including slice and map overhead
+func (se sortEntry) size() int {
+	size := len(se.key) + 64
+
+	for _, edge := range se.result.edges {
+		size += len(edge.subject) + len(edge.predicate) + valueSize(edge.object) + 96
+	}
+
+	for name, variable := range se.result.variables {
+		size += len(name) + valueSize(variable.value) + 64
+	}
+
+	return size
+}
+
+func (se sortEntry) pack() []byte {
+	edges := make(tuple.Tuple, len(se.result.edges))
+	for i, edge := range se.result.edges {
+		edges[i] = tuple.Tuple{ edge.subject, edge.predicate, encodeValue(edge.object) }
+	}
+
+	variables := make(tuple.Tuple, 0, 2 * len(se.result.variables))
+	for name, variable := range se.result.variables {
+		variables = append(variables, name, encodeValue(variable.value))
+	}
+
+	return tuple.Tuple{ se.key, edges, variables }.Pack()
+}
+
+func unpackSortEntry(packed []byte) (sortEntry, error) {
+	unpacked, e := tuple.Unpack(packed)
+
+	if e != nil {
+		return sortEntry{}, e
+	}
+
+	if len(unpacked) != 3 {
+		return sortEntry{}, fmt.Errorf("malformed spilled sort entry %x", packed)
+	}
+
+	key, keyOk := unpacked[0].([]byte)
+	edges, edgesOk := unpacked[1].(tuple.Tuple)
+	variables, variablesOk := unpacked[2].(tuple.Tuple)
+
+	if !keyOk || !edgesOk || !variablesOk {
+		return sortEntry{}, fmt.Errorf("malformed spilled sort entry %x", packed)
+	}
+
+	result := &SearchResults{
+		edges:     make([]*Edge, len(edges)),
+		variables: make(map[string]*VariableResult, len(variables) / 2),
+	}
+
+	for i, element := range edges {
+		fields, ok := element.(tuple.Tuple)
+
+		if !ok || len(fields) != 3 {
+			return sortEntry{}, fmt.Errorf("malformed spilled sort entry %x", packed)
+		}
+
+		edge := &Edge{}
+		edge.subject, _ = fields[0].([]byte)
+		edge.predicate, _ = fields[1].([]byte)
+
+		if edge.object, e = decodeValue(fields[2]); e != nil {
+			return sortEntry{}, e
+		}
+
+		result.edges[i] = edge
+	}
+
+	for i := 0; i + 1 < len(variables); i += 2 {
+		name, _ := variables[i].(string)
+		value, e := decodeValue(variables[i + 1])
+
+		if e != nil {
+			return sortEntry{}, e
+		}
+
+		result.variables[name] = &VariableResult{ name: name, value: value }
+	}
+
+	return sortEntry{ key: key, result: result }, nil
+}
+
+// sortRun is a sorted run of entries, either spilled to a temporary file or still buffered in memory. A spilled
+// run's file is only held open while it's written and merged.
+type sortRun struct {
+	path     string
+	file     *os.File
+	writer   *bufio.Writer
+	reader   *bufio.Reader
+	buffered []sortEntry
+	current  *sortEntry
+}
+
+// newSpilledRun creates an empty run in a temporary file, to be written in order and then finished
+func newSpilledRun() (*sortRun, error) {
+	file, e := ioutil.TempFile("", "simplegraph-sort-")
+
+	if e != nil {
+		return nil, e
+	}
+
+	return &sortRun{ path: file.Name(), file: file, writer: bufio.NewWriter(file) }, nil
+}
+
+// spillRun writes sorted entries to a temporary file. The run is returned even on failure, so it can be closed.
+func spillRun(entries []sortEntry) (*sortRun, error) {
+	run, e := newSpilledRun()
+
+	if e != nil {
+		return nil, e
+	}
+
+	for i := range entries {
+		if e := run.write(&entries[i]); e != nil {
+			return run, e
+		}
+	}
+
+	return run, run.finish()
+}
+
+// write appends an entry to a spilled run, prefixed by its packed length
+func (run *sortRun) write(entry *sortEntry) error {
+	packed := entry.pack()
+	var lengthPrefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lengthPrefix[:], uint64(len(packed)))
+
+	if _, e := run.writer.Write(lengthPrefix[:n]); e != nil {
+		return e
+	}
+
+	_, e := run.writer.Write(packed)
+	return e
+}
+
+// finish flushes a spilled run and closes its file until it's merged
+func (run *sortRun) finish() error {
+	e := run.writer.Flush()
+	run.writer = nil
+
+	if closeErr := run.file.Close(); e == nil {
+		e = closeErr
+	}
+
+	run.file = nil
+	return e
+}
+
+// open reopens a spilled run's file for reading
+func (run *sortRun) open() error {
+	if run.path == "" {
+		return nil
+	}
+
+	file, e := os.Open(run.path)
+
+	if e != nil {
+		return e
+	}
+
+	run.file = file
+	run.reader = bufio.NewReader(file)
+	return nil
+}
+
+// advance moves current to the next entry in the run, or to nil once the run is exhausted
+func (run *sortRun) advance() error {
+	run.current = nil
+
+	if run.path == "" {
+		if len(run.buffered) > 0 {
+			run.current = &run.buffered[0]
+			run.buffered = run.buffered[1:]
+		}
+
+		return nil
+	}
+
+	length, e := binary.ReadUvarint(run.reader)
+
+	if e == io.EOF {
+		return nil
+	} else if e != nil {
+		return e
+	}
+
+	packed := make([]byte, length)
+	if _, e := io.ReadFull(run.reader, packed); e != nil {
+		return e
+	}
+
+	entry, e := unpackSortEntry(packed)
+
+	if e != nil {
+		return e
+	}
+
+	run.current = &entry
+	return nil
+}
+
+// close closes and removes a spilled run's file. Closing a run twice is harmless.
+func (run *sortRun) close() {
+	if run.file != nil {
+		_ = run.file.Close()
+		run.file = nil
+	}
+
+	if run.path != "" {
+		_ = os.Remove(run.path)
+		run.path = ""
+	}
+}
+
+type sortResults []sortEntry
 
 func (b sortResults) Len() int {
 	return len(b)
 }
 
 func (b sortResults) Less(i, j int) bool {
-	// todo: this is inefficient to re-pack the byte arrays each time
-	switch bytes.Compare(b[i].edge.toBytes(), b[j].edge.toBytes()) {
+	switch bytes.Compare(b[i].key, b[j].key) {
 	case -1:
 		return true
 	case 0, 1:
@@ -54,3 +424,29 @@ func (b sortResults) Less(i, j int) bool {
 func (b sortResults) Swap(i, j int) {
 	b[j], b[i] = b[i], b[j]
 }
+
+// runHeap orders runs by their current entry, for merging
+type runHeap []*sortRun
+
+func (h runHeap) Len() int {
+	return len(h)
+}
+
+func (h runHeap) Less(i, j int) bool {
+	return bytes.Compare(h[i].current.key, h[j].current.key) < 0
+}
+
+func (h runHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	*h = append(*h, x.(*sortRun))
+}
+
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	run := old[len(old) - 1]
+	*h = old[:len(old) - 1]
+	return run
+}
@@ -0,0 +1,139 @@
+package simplegraph
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSortStream_join(t *testing.T) {
+	tests := []struct {
+		name         string
+		memoryBudget int
+		fanIn        int
+	}{
+		{"it sorts in memory", 0, 0},
+		{"it spills sorted runs past the memory budget", 1024, 0},
+		{"it spills every result with a tiny budget", 1, 0},
+		{"it merges more runs than the fan-in in passes", 1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fanIn > 0 {
+				defer func(fanIn int) { maxSortFanIn = fanIn }(maxSortFanIn)
+				maxSortFanIn = tt.fanIn
+			}
+
+			var input []map[string]string
+			var want []string
+
+			for i := 0; i < 200; i++ {
+				// insert out of order, with a duplicate key for every value of y
+				input = append(input, map[string]string{"x": fmt.Sprintf("x%03d", i), "y": fmt.Sprintf("y%03d", (i*37)%100)})
+			}
+
+			for i := 0; i < 100; i++ {
+				want = append(want, fmt.Sprintf("y%03d", i), fmt.Sprintf("y%03d", i))
+			}
+
+			filesBefore := spillFiles(t)
+
+			sort := SortStream{tripleOrder: TripleOrder{variableOrder: []string{"y"}}, memoryBudget: tt.memoryBudget}
+			errs := &pipelineError{}
+
+			var got []string
+			seen := make(map[string]bool)
+			mostOpen := 0
+			for result := range sort.join(context.Background(), searchResultsChannel(input...), errs) {
+				if open := openSpillFiles(t); open > mostOpen {
+					mostOpen = open
+				}
+
+				y, _ := result.Get("y")
+				x, _ := result.Get("x")
+
+				got = append(got, string(y.([]byte)))
+				seen[string(x.([]byte))] = true
+			}
+
+			if e := errs.get(); e != nil {
+				t.Fatalf("SortStream.join() error = %v", e)
+			}
+
+			if !reflect.DeepEqual(got, want) || len(seen) != len(input) {
+				t.Errorf("SortStream.join() = %v, want %v", got, want)
+			}
+
+			if filesAfter := spillFiles(t); filesAfter != filesBefore {
+				t.Errorf("SortStream.join() left %v spill files behind", filesAfter-filesBefore)
+			}
+
+			if tt.fanIn > 0 && mostOpen > tt.fanIn {
+				t.Errorf("SortStream.join() held %v spill files open, want at most %v", mostOpen, tt.fanIn)
+			}
+		})
+	}
+}
+
+func TestSortEntry_pack(t *testing.T) {
+	edge := NewEdge([]byte("S"), []byte("P"), []byte{0x00, 0xff})
+	entry := sortEntry{
+		key: []byte("key"),
+		result: &SearchResults{
+			edges:     []*Edge{&edge, &edge},
+			variables: map[string]*VariableResult{"x": {name: "x", value: []byte("S")}},
+		},
+	}
+
+	got, e := unpackSortEntry(entry.pack())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !reflect.DeepEqual(got, entry) {
+		t.Errorf("unpackSortEntry() = %v, want %v", got, entry)
+	}
+}
+
+func spillFiles(t *testing.T) int {
+	files, e := ioutil.ReadDir(os.TempDir())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	count := 0
+	for _, file := range files {
+		if len(file.Name()) > len("simplegraph-sort-") && file.Name()[:len("simplegraph-sort-")] == "simplegraph-sort-" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// openSpillFiles counts the spill files this process holds open, where /proc lists them
+func openSpillFiles(t *testing.T) int {
+	fds, e := ioutil.ReadDir("/proc/self/fd")
+
+	if e != nil {
+		return 0
+	}
+
+	count := 0
+	for _, fd := range fds {
+		target, e := os.Readlink("/proc/self/fd/" + fd.Name())
+
+		if e == nil && strings.HasPrefix(filepath.Base(target), "simplegraph-sort-") {
+			count++
+		}
+	}
+
+	return count
+}
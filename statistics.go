@@ -0,0 +1,623 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+const histogramBuckets = 16
+
+// Statistics summarise the shape of a graph for the query planner. They're gathered by SimpleGraph.Analyze, which
+// saves them in the store for LoadStatistics to read back when the graph is next opened. They aren't updated as edges
+// are added or deleted, so they should be refreshed after large changes to the graph.
+type Statistics struct {
+	Edges            int64
+	DistinctSubjects int64
+	DistinctObjects  int64
+	Predicates       map[string]*PredicateStatistics
+}
+
+type PredicateStatistics struct {
+	Edges            int64
+	DistinctSubjects int64
+	DistinctObjects  int64
+
+	// ObjectHistogram splits the predicate's objects into buckets of roughly equal numbers of edges, in object order.
+	// Edges with the same object always fall in the same bucket.
+	ObjectHistogram []HistogramBucket
+}
+
+type HistogramBucket struct {
+	// UpperBound is the largest object in the bucket
+	UpperBound       interface{}
+	Edges            int64
+	DistinctObjects  int64
+}
+
+// Analyze scans the graph to gather the Statistics the query planner uses to order joins and choose join
+// algorithms, and keeps them for later queries, saving them in the store too. It reads every key of four of the six
+// indices.
+func (graph *SimpleGraph) Analyze(ctx context.Context) (*Statistics, error) {
+	stats := &Statistics{
+		Predicates: make(map[string]*PredicateStatistics),
+	}
+
+	// pso groups each predicate's subjects together
+	var predicate, subject []byte
+	e := graph.scanIndex(ctx, Indices["pso"], func(edge *Edge) {
+		if !bytes.Equal(edge.predicate, predicate) || predicate == nil {
+			predicate, subject = edge.predicate, nil
+			stats.Predicates[string(predicate)] = &PredicateStatistics{}
+		}
+
+		predicateStats := stats.Predicates[string(predicate)]
+		predicateStats.Edges++
+		stats.Edges++
+
+		if !bytes.Equal(edge.subject, subject) || subject == nil {
+			subject = edge.subject
+			predicateStats.DistinctSubjects++
+		}
+	})
+
+	if e != nil {
+		return nil, e
+	}
+
+	// pos groups each predicate's objects together, in order, so the histograms can be built as it's read
+	var object interface{}
+	var bucket *HistogramBucket
+	predicate = nil
+	e = graph.scanIndex(ctx, Indices["pos"], func(edge *Edge) {
+		predicateStats := stats.Predicates[string(edge.predicate)]
+
+		if predicateStats == nil {
+			// written between the two scans
+			return
+		}
+
+		if !bytes.Equal(edge.predicate, predicate) || predicate == nil {
+			predicate, object, bucket = edge.predicate, nil, nil
+		}
+
+		if object == nil || !valuesEqual(edge.object, object) {
+			object = edge.object
+			predicateStats.DistinctObjects++
+
+			depth := int64(math.Ceil(float64(predicateStats.Edges) / histogramBuckets))
+			if bucket == nil || bucket.Edges >= depth {
+				predicateStats.ObjectHistogram = append(predicateStats.ObjectHistogram, HistogramBucket{})
+				bucket = &predicateStats.ObjectHistogram[len(predicateStats.ObjectHistogram) - 1]
+			}
+
+			bucket.DistinctObjects++
+			bucket.UpperBound = object
+		}
+
+		bucket.Edges++
+	})
+
+	if e != nil {
+		return nil, e
+	}
+
+	subject = nil
+	e = graph.scanIndex(ctx, Indices["spo"], func(edge *Edge) {
+		if !bytes.Equal(edge.subject, subject) || subject == nil {
+			subject = edge.subject
+			stats.DistinctSubjects++
+		}
+	})
+
+	if e != nil {
+		return nil, e
+	}
+
+	object = nil
+	e = graph.scanIndex(ctx, Indices["osp"], func(edge *Edge) {
+		if object == nil || !valuesEqual(edge.object, object) {
+			object = edge.object
+			stats.DistinctObjects++
+		}
+	})
+
+	if e != nil {
+		return nil, e
+	}
+
+	if e := graph.saveStatistics(ctx, stats); e != nil {
+		return nil, e
+	}
+
+	graph.statsMu.Lock()
+	graph.stats = stats
+	graph.statsMu.Unlock()
+
+	return stats, nil
+}
+
+// statisticsSubspace holds the statistics Analyze saves, packed and split into chunks that fit any store's keys. Each
+// Analyze writes a new generation of chunks in one call to the store, then deletes the older generations, so the
+// latest generation is always whole.
+var statisticsSubspace = subspace.Sub("statistics")
+
+// statisticsChunkSize keeps each key well under FoundationDB's 10kB limit
+const statisticsChunkSize = 8000
+
+var errMalformedStatistics = errors.New("malformed saved statistics")
+
+// LoadStatistics reads the statistics the last Analyze saved in the store, and keeps them for later queries as
+// Analyze does. It returns nil if the graph has never been analyzed.
+func (graph *SimpleGraph) LoadStatistics(ctx context.Context) (*Statistics, error) {
+	chunks, e := graph.statisticsChunks(ctx)
+
+	if e != nil || len(chunks) == 0 {
+		return nil, e
+	}
+
+	var packed []byte
+	latest := chunks[len(chunks) - 1].generation
+
+	for _, chunk := range chunks {
+		if chunk.generation == latest {
+			packed = append(packed, chunk.data...)
+		}
+	}
+
+	stats, e := unpackStatistics(packed)
+
+	if e != nil {
+		return nil, e
+	}
+
+	graph.statsMu.Lock()
+	graph.stats = stats
+	graph.statsMu.Unlock()
+
+	return stats, nil
+}
+
+type statisticsChunk struct {
+	key        []byte
+	generation int64
+	data       []byte
+}
+
+// statisticsChunks reads every saved chunk, oldest generation first, each in order
+func (graph *SimpleGraph) statisticsChunks(ctx context.Context) ([]statisticsChunk, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan []byte)
+	storeError := make(chan error, 1)
+
+	go func() {
+		storeError <- graph.kvstore.Get(ctx, statisticsSubspace.Bytes(), keys)
+	}()
+
+	var chunks []statisticsChunk
+	var e error
+
+	for key := range keys {
+		if e != nil {
+			continue
+		}
+
+		unpacked, unpackErr := statisticsSubspace.Unpack(fdb.Key(key))
+
+		if unpackErr != nil || len(unpacked) != 3 {
+			e = errMalformedStatistics
+			cancel()
+			continue
+		}
+
+		generation, generationOk := unpacked[0].(int64)
+		data, dataOk := unpacked[2].([]byte)
+
+		if !generationOk || !dataOk {
+			e = errMalformedStatistics
+			cancel()
+			continue
+		}
+
+		chunks = append(chunks, statisticsChunk{ key: key, generation: generation, data: data })
+	}
+
+	if storeErr := <-storeError; e == nil {
+		e = storeErr
+	}
+
+	if e != nil {
+		return nil, e
+	}
+
+	return chunks, nil
+}
+
+// saveStatistics writes the statistics as a new generation, then deletes the generations before it
+func (graph *SimpleGraph) saveStatistics(ctx context.Context, stats *Statistics) error {
+	old, e := graph.statisticsChunks(ctx)
+
+	if e != nil {
+		return e
+	}
+
+	generation := int64(1)
+	if len(old) > 0 {
+		generation = old[len(old) - 1].generation + 1
+	}
+
+	packed := stats.pack()
+	var keys [][]byte
+
+	for i := 0; i * statisticsChunkSize < len(packed); i++ {
+		end := (i + 1) * statisticsChunkSize
+		if end > len(packed) {
+			end = len(packed)
+		}
+
+		keys = append(keys, statisticsSubspace.Pack(tuple.Tuple{ generation, int64(i), packed[i * statisticsChunkSize:end] }))
+	}
+
+	if e := graph.kvstore.Put(keys...); e != nil {
+		return e
+	}
+
+	if len(old) == 0 {
+		return nil
+	}
+
+	oldKeys := make([][]byte, len(old))
+	for i, chunk := range old {
+		oldKeys[i] = chunk.key
+	}
+
+	return graph.kvstore.Delete(oldKeys...)
+}
+
+func (stats *Statistics) pack() []byte {
+	predicates := make(tuple.Tuple, 0, len(stats.Predicates))
+
+	for name, ps := range stats.Predicates {
+		histogram := make(tuple.Tuple, len(ps.ObjectHistogram))
+
+		for i, bucket := range ps.ObjectHistogram {
+			histogram[i] = tuple.Tuple{ encodeValue(bucket.UpperBound), bucket.Edges, bucket.DistinctObjects }
+		}
+
+		predicates = append(predicates, tuple.Tuple{ []byte(name), ps.Edges, ps.DistinctSubjects, ps.DistinctObjects, histogram })
+	}
+
+	return tuple.Tuple{ stats.Edges, stats.DistinctSubjects, stats.DistinctObjects, predicates }.Pack()
+}
+
+func unpackStatistics(packed []byte) (*Statistics, error) {
+	unpacked, e := tuple.Unpack(packed)
+
+	if e != nil {
+		return nil, e
+	}
+
+	if len(unpacked) != 4 {
+		return nil, errMalformedStatistics
+	}
+
+	stats := &Statistics{ Predicates: make(map[string]*PredicateStatistics) }
+	predicates, ok := unpacked[3].(tuple.Tuple)
+
+	if !ok || !unpackCounts(unpacked, &stats.Edges, &stats.DistinctSubjects, &stats.DistinctObjects) {
+		return nil, errMalformedStatistics
+	}
+
+	for _, element := range predicates {
+		fields, ok := element.(tuple.Tuple)
+
+		if !ok || len(fields) != 5 {
+			return nil, errMalformedStatistics
+		}
+
+		ps := &PredicateStatistics{}
+		name, nameOk := fields[0].([]byte)
+		histogram, histogramOk := fields[4].(tuple.Tuple)
+
+		if !nameOk || !histogramOk || !unpackCounts(fields[1:4], &ps.Edges, &ps.DistinctSubjects, &ps.DistinctObjects) {
+			return nil, errMalformedStatistics
+		}
+
+		for _, element := range histogram {
+			bucket, ok := element.(tuple.Tuple)
+
+			if !ok || len(bucket) != 3 {
+				return nil, errMalformedStatistics
+			}
+
+			upperBound, e := decodeValue(bucket[0])
+
+			if e != nil {
+				return nil, e
+			}
+
+			ps.ObjectHistogram = append(ps.ObjectHistogram, HistogramBucket{ UpperBound: upperBound })
+			last := &ps.ObjectHistogram[len(ps.ObjectHistogram) - 1]
+
+			if !unpackCounts(bucket[1:], &last.Edges, &last.DistinctObjects) {
+				return nil, errMalformedStatistics
+			}
+		}
+
+		stats.Predicates[string(name)] = ps
+	}
+
+	return stats, nil
+}
+
+// unpackCounts reads the leading elements of a tuple into counts, reporting whether they were all integers
+func unpackCounts(elements tuple.Tuple, counts ...*int64) bool {
+	if len(elements) < len(counts) {
+		return false
+	}
+
+	for i, count := range counts {
+		value, ok := elements[i].(int64)
+
+		if !ok {
+			return false
+		}
+
+		*count = value
+	}
+
+	return true
+}
+
+// Statistics returns the statistics gathered by the last call to Analyze or LoadStatistics, or nil if neither has
+// found any
+func (graph *SimpleGraph) Statistics() *Statistics {
+	graph.statsMu.Lock()
+	defer graph.statsMu.Unlock()
+
+	return graph.stats
+}
+
+// estimateSource names what the model's estimates come from, for Explain
+func (model *costModel) estimateSource() string {
+	if model.stats == nil {
+		return "guesses"
+	}
+
+	return "statistics"
+}
+
+func (graph *SimpleGraph) costModel() *costModel {
+	return &costModel{
+		stats:        graph.Statistics(),
+		memoryBudget: graph.sortMemoryBudget,
+	}
+}
+
+// scanIndex calls visit with every edge in an index, in index order
+func (graph *SimpleGraph) scanIndex(ctx context.Context, idx *hexastoreIndex, visit func(edge *Edge)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := &pipelineError{}
+
+	for edge := range graph._getRangeStreaming(ctx, idx, keySpan{ prefix: idx.ss.Bytes() }, errs, nil) {
+		visit(edge)
+	}
+
+	if e := errs.get(); e != nil {
+		return e
+	}
+
+	return ctx.Err()
+}
+
+// defaultCardinality is the number of edges the planner assumes a graph has when it hasn't been analyzed. Each fixed
+// field of a pattern is assumed to match 1 in defaultSelectivity of them.
+const (
+	defaultCardinality = 1e6
+	defaultSelectivity = 100
+
+	// defaultRangeSelectivity is the fraction of objects a range is assumed to match without a histogram
+	defaultRangeSelectivity = 1.0 / 3
+)
+
+// estimate is the planner's guess at how many results a plan node produces, and how many distinct values each of
+// its variables takes
+type estimate struct {
+	rows     float64
+	distinct map[string]float64
+}
+
+// costModel estimates the cost of plans from the graph's statistics, or from fixed guesses if there are none
+type costModel struct {
+	stats        *Statistics
+	memoryBudget int
+
+	// analyze has every plan node measure itself as it runs
+	analyze bool
+}
+
+func (model *costModel) newMetrics() *OperatorMetrics {
+	if !model.analyze {
+		return nil
+	}
+
+	return &OperatorMetrics{}
+}
+
+// estimatedRowSize is the number of bytes a buffered result is assumed to take, to check it'll fit in the budget
+const estimatedRowSize = 256
+
+func (model *costModel) scanEstimate(query Query) *estimate {
+	fixed := transformQuery(query)
+	variables := query.toVariableMap()
+
+	if model.stats == nil {
+		rows := defaultCardinality / math.Pow(defaultSelectivity, float64(len(fixed)))
+
+		if query.objectRange != nil {
+			rows = rows * defaultRangeSelectivity
+		}
+
+		distinct := make(map[string]float64)
+
+		for _, variable := range variables {
+			distinct[variable] = rows
+		}
+
+		return &estimate{ rows: rows, distinct: distinct }
+	}
+
+	stats := model.stats
+	rows := float64(stats.Edges)
+	distinctSubjects := float64(stats.DistinctSubjects)
+	distinctObjects := float64(stats.DistinctObjects)
+	distinctPredicates := float64(len(stats.Predicates))
+
+	if predicate, ok := fixed[PREDICATE]; ok {
+		predicateStats := stats.Predicates[string(predicate.([]byte))]
+
+		if predicateStats == nil {
+			return &estimate{ rows: 0, distinct: map[string]float64{} }
+		}
+
+		rows = float64(predicateStats.Edges)
+		distinctSubjects = float64(predicateStats.DistinctSubjects)
+		distinctObjects = float64(predicateStats.DistinctObjects)
+		distinctPredicates = 1
+
+		if object, ok := fixed[OBJECT]; ok {
+			rows = predicateStats.objectEstimate(object)
+			distinctObjects = 1
+		} else if query.objectRange != nil {
+			fraction := predicateStats.rangeFraction(*query.objectRange)
+			rows, distinctObjects = rows * fraction, distinctObjects * fraction
+		}
+
+		if _, ok := fixed[SUBJECT]; ok {
+			rows = rows / math.Max(distinctSubjects, 1)
+			distinctSubjects = 1
+		}
+	} else {
+		if _, ok := fixed[SUBJECT]; ok {
+			rows = rows / math.Max(distinctSubjects, 1)
+			distinctSubjects = 1
+		}
+
+		if query.objectRange != nil {
+			rows, distinctObjects = rows * defaultRangeSelectivity, distinctObjects * defaultRangeSelectivity
+		}
+
+		if _, ok := fixed[OBJECT]; ok {
+			rows = rows / math.Max(distinctObjects, 1)
+			distinctObjects = 1
+		}
+	}
+
+	distinct := make(map[string]float64)
+
+	for dataField, variable := range variables {
+		switch dataField {
+		case SUBJECT:
+			distinct[variable] = math.Min(distinctSubjects, rows)
+		case PREDICATE:
+			distinct[variable] = math.Min(distinctPredicates, rows)
+		case OBJECT:
+			distinct[variable] = math.Min(distinctObjects, rows)
+		}
+	}
+
+	return &estimate{ rows: rows, distinct: distinct }
+}
+
+// rangeFraction guesses the fraction of the predicate's edges with objects in the range, from the histogram buckets
+// whose upper bounds fall in it. A range narrower than a bucket is assumed to cover half of one.
+func (ps *PredicateStatistics) rangeFraction(valueRange ValueRange) float64 {
+	if ps.Edges == 0 || len(ps.ObjectHistogram) == 0 {
+		return defaultRangeSelectivity
+	}
+
+	var edges int64
+	for _, bucket := range ps.ObjectHistogram {
+		if valueRange.contains(bucket.UpperBound) {
+			edges += bucket.Edges
+		}
+	}
+
+	fraction := float64(edges) / float64(ps.Edges)
+	return math.Max(fraction, 0.5 / float64(len(ps.ObjectHistogram)))
+}
+
+// objectEstimate guesses how many of the predicate's edges have the given object, from the histogram bucket it falls in
+func (ps *PredicateStatistics) objectEstimate(object interface{}) float64 {
+	for _, bucket := range ps.ObjectHistogram {
+		if compareValues(object, bucket.UpperBound) <= 0 {
+			return float64(bucket.Edges) / math.Max(float64(bucket.DistinctObjects), 1)
+		}
+	}
+
+	return 0
+}
+
+// joinEstimate guesses the size of a join as |left| * |right| / the larger distinct count of its most selective
+// shared variable, which assumes the side with fewer distinct values has all of its values on the other side
+func joinEstimate(left, right *estimate, shared []string) *estimate {
+	rows := left.rows * right.rows
+	divisor := 1.0
+
+	for _, variable := range shared {
+		divisor = math.Max(divisor, math.Max(left.distinct[variable], right.distinct[variable]))
+	}
+
+	rows = rows / divisor
+	distinct := make(map[string]float64)
+
+	for variable, count := range left.distinct {
+		distinct[variable] = math.Min(count, rows)
+	}
+
+	for variable, count := range right.distinct {
+		if existing, ok := distinct[variable]; ok {
+			count = math.Min(existing, count)
+		}
+
+		distinct[variable] = math.Min(count, rows)
+	}
+
+	return &estimate{ rows: rows, distinct: distinct }
+}
+
+// hashFits reports whether the smaller side of a join can be hashed within the memory budget
+func (model *costModel) hashFits(left, right *estimate) bool {
+	memoryBudget := model.memoryBudget
+	if memoryBudget <= 0 {
+		memoryBudget = defaultSortMemoryBudget
+	}
+
+	return math.Min(left.rows, right.rows) * estimatedRowSize <= float64(memoryBudget)
+}
+
+// hashJoinCost and sortMergeJoinCost count the results each algorithm touches: both read their inputs once, a hash
+// join then builds a table from the smaller side, and a sort-merge join sorts the left, and the right too unless its
+// scan is already in join order
+func hashJoinCost(left, right *estimate) float64 {
+	return left.rows + right.rows + math.Min(left.rows, right.rows)
+}
+
+func sortMergeJoinCost(left, right *estimate, rightSorted bool) float64 {
+	cost := left.rows + right.rows + left.rows * math.Log2(left.rows + 1)
+
+	if !rightSorted {
+		cost += right.rows * math.Log2(right.rows + 1)
+	}
+
+	return cost
+}
@@ -0,0 +1,109 @@
+package simplegraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSimpleGraph_Analyze(t *testing.T) {
+	graph := friendsGraph()
+
+	if graph.Statistics() != nil {
+		t.Errorf("expected no statistics before Analyze")
+	}
+
+	stats, e := graph.Analyze(context.Background())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if stats.Edges != 12 || stats.DistinctSubjects != 6 || stats.DistinctObjects != 7 {
+		t.Errorf("Analyze() = %v edges, %v subjects, %v objects, want 12, 6, 7",
+			stats.Edges, stats.DistinctSubjects, stats.DistinctObjects)
+	}
+
+	friend := stats.Predicates["friend"]
+	if friend == nil || friend.Edges != 10 || friend.DistinctSubjects != 6 || friend.DistinctObjects != 6 {
+		t.Errorf("Analyze() friend = %+v, want 10 edges, 6 subjects, 6 objects", friend)
+	}
+
+	var histogramEdges, histogramObjects int64
+	for _, bucket := range friend.ObjectHistogram {
+		histogramEdges += bucket.Edges
+		histogramObjects += bucket.DistinctObjects
+	}
+
+	if histogramEdges != 10 || histogramObjects != 6 {
+		t.Errorf("expected the histogram to cover every friend edge, got %+v", friend.ObjectHistogram)
+	}
+
+	worksAt := stats.Predicates["works at"]
+	want := &PredicateStatistics{
+		Edges:            2,
+		DistinctSubjects: 2,
+		DistinctObjects:  1,
+		ObjectHistogram:  []HistogramBucket{{ UpperBound: []byte("acme"), Edges: 2, DistinctObjects: 1 }},
+	}
+
+	if !reflect.DeepEqual(worksAt, want) {
+		t.Errorf("Analyze() works at = %+v, want %+v", worksAt, want)
+	}
+
+	if graph.Statistics() != stats {
+		t.Errorf("expected Analyze to keep its statistics for the planner")
+	}
+}
+
+func TestSimpleGraph_LoadStatistics(t *testing.T) {
+	store := NewMemoryGraph()
+	graph := NewSimpleGraph(store)
+
+	// enough predicates that the statistics take several chunks
+	var edges []Edge
+	for i := 0; i < 200; i++ {
+		predicate := []byte(fmt.Sprintf("predicate %03d %v", i, strings.Repeat("x", 50)))
+		edges = append(edges, NewEdge([]byte("paul"), predicate, int64(i)), NewEdge([]byte("jess"), predicate, "celtics"))
+	}
+
+	_ = graph.AddEdges(edges)
+
+	if stats, e := NewSimpleGraph(store).LoadStatistics(context.Background()); stats != nil || e != nil {
+		t.Errorf("LoadStatistics() = %v, %v before Analyze, want nothing", stats, e)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, e := graph.Analyze(context.Background()); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	chunks, e := graph.statisticsChunks(context.Background())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if len(chunks) < 2 || chunks[0].generation != 2 || chunks[len(chunks) - 1].generation != 2 {
+		t.Errorf("expected only the latest generation of statistics, in several chunks, got %v chunks", len(chunks))
+	}
+
+	reopened := NewSimpleGraph(store)
+	stats, e := reopened.LoadStatistics(context.Background())
+
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if !reflect.DeepEqual(stats, graph.Statistics()) || reopened.Statistics() != stats {
+		t.Errorf("LoadStatistics() = %+v, want %+v", stats, graph.Statistics())
+	}
+
+	plan, e := reopened.Explain(NewQuery().WithSubjectVariable("x").WithPredicate(edges[0].predicate).WithObjectVariable("y"))
+
+	if e != nil || plan.Estimates != "statistics" || plan.EstimatedRows != 2 {
+		t.Errorf("Explain() = %v, %v, want estimates from statistics", plan, e)
+	}
+}
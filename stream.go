@@ -0,0 +1,149 @@
+package simplegraph
+
+import (
+	"context"
+	"sync"
+)
+
+// pipelineError records the first error raised by any stage of a streaming query. Every stage that can fail shares
+// the same pipelineError, and must record its error before closing its output channel so that the failure is
+// visible to whoever observes the end of the stream. Once the stream has been handed its final result it is
+// sealed, so stages torn down after a successful query can't retroactively fail it.
+type pipelineError struct {
+	mu     sync.Mutex
+	e      error
+	sealed bool
+}
+
+func (pe *pipelineError) set(e error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	if pe.e == nil && !pe.sealed {
+		pe.e = e
+	}
+}
+
+func (pe *pipelineError) seal() {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.sealed = true
+}
+
+func (pe *pipelineError) get() error {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	return pe.e
+}
+
+// EdgeStream is the result of a streaming edge query. Range over Edges until it is closed, then check Err: if any
+// stage of the query failed, or its context was cancelled, the stream ends early and Err reports why.
+type EdgeStream struct {
+	edges  <-chan *Edge
+	errs   *pipelineError
+	cancel context.CancelFunc
+	page   *page
+}
+
+// newEdgeStream hands the output of the final stage of a pipeline to the caller. When that output is exhausted it
+// cancels the pipeline's context, which releases any stage a join stopped reading from part way through.
+func newEdgeStream(ctx context.Context, cancel context.CancelFunc, input <-chan *Edge, errs *pipelineError, page *page) *EdgeStream {
+	output := make(chan *Edge)
+
+	go func() {
+		defer close(output)
+		defer cancel()
+		defer errs.seal()
+
+		for edge := range input {
+			select {
+			case output <- edge:
+			case <-ctx.Done():
+				errs.set(ctx.Err())
+				return
+			}
+		}
+
+		if e := ctx.Err(); e != nil {
+			errs.set(e)
+		}
+	}()
+
+	return &EdgeStream{edges: output, errs: errs, cancel: cancel, page: page}
+}
+
+func (es *EdgeStream) Edges() <-chan *Edge {
+	return es.edges
+}
+
+// Err returns the first error the query hit, if any. It is only meaningful once Edges has been closed.
+func (es *EdgeStream) Err() error {
+	return es.errs.get()
+}
+
+// Close stops the query and releases its goroutines and any open read. It's only needed when the caller stops
+// reading before Edges is closed, but is always safe to call.
+func (es *EdgeStream) Close() {
+	es.cancel()
+}
+
+// Cursor resumes a limited query after the last edge it returned, or is empty if there are no more. It is only
+// meaningful once Edges has been closed.
+func (es *EdgeStream) Cursor() Cursor {
+	return es.page.cursor()
+}
+
+// SearchStream is the result of a streaming variable search. Range over Results until it is closed, then check Err.
+type SearchStream struct {
+	results <-chan *SearchResults
+	errs    *pipelineError
+	cancel  context.CancelFunc
+	page    *page
+}
+
+func newSearchStream(ctx context.Context, cancel context.CancelFunc, input <-chan *SearchResults, errs *pipelineError, page *page) *SearchStream {
+	output := make(chan *SearchResults)
+
+	go func() {
+		defer close(output)
+		defer cancel()
+		defer errs.seal()
+
+		for result := range input {
+			select {
+			case output <- result:
+			case <-ctx.Done():
+				errs.set(ctx.Err())
+				return
+			}
+		}
+
+		if e := ctx.Err(); e != nil {
+			errs.set(e)
+		}
+	}()
+
+	return &SearchStream{results: output, errs: errs, cancel: cancel, page: page}
+}
+
+func (ss *SearchStream) Results() <-chan *SearchResults {
+	return ss.results
+}
+
+// Err returns the first error the search hit, if any. It is only meaningful once Results has been closed.
+func (ss *SearchStream) Err() error {
+	return ss.errs.get()
+}
+
+// Close stops the search and releases its goroutines and any open read.
+func (ss *SearchStream) Close() {
+	ss.cancel()
+}
+
+// Cursor resumes a limited search after the last result it returned, or is empty if there are no more. It is only
+// meaningful once Results has been closed.
+func (ss *SearchStream) Cursor() Cursor {
+	return ss.page.cursor()
+}
@@ -0,0 +1,203 @@
+package simplegraph
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TurtleReader reads edges from RDF Turtle. It understands prefixes, a base IRI, the a keyword, the ; and ,
+// shorthand, bare numbers and booleans, and blank node property lists, but not collections.
+type TurtleReader struct {
+	parser *rdfParser
+}
+
+func NewTurtleReader(r io.Reader) *TurtleReader {
+	return &TurtleReader{ parser: newRDFParser(r, true) }
+}
+
+// Read returns the next edge, or io.EOF once the input is exhausted. A syntax error is a *LineError.
+func (tr *TurtleReader) Read() (Edge, error) {
+	return tr.parser.read()
+}
+
+// TurtleWriter writes edges as RDF Turtle, shortening IRIs with its prefixes, which map each prefix to its
+// namespace. Consecutive edges with the same subject share it with ;, and with the same predicate too, with ,. Call
+// Close once every edge is written.
+type TurtleWriter struct {
+	w        *bufio.Writer
+	prefixes map[string]string
+
+	// namespaces are tried longest first, so the most specific prefix wins
+	namespaces []string
+	started    bool
+
+	subject, predicate []byte
+}
+
+func NewTurtleWriter(w io.Writer, prefixes map[string]string) *TurtleWriter {
+	tw := &TurtleWriter{ w: bufio.NewWriter(w), prefixes: prefixes }
+
+	for prefix := range prefixes {
+		tw.namespaces = append(tw.namespaces, prefix)
+	}
+
+	sort.Slice(tw.namespaces, func(i, j int) bool {
+		a, b := prefixes[tw.namespaces[i]], prefixes[tw.namespaces[j]]
+
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+
+		return tw.namespaces[i] < tw.namespaces[j]
+	})
+
+	return tw
+}
+
+func (tw *TurtleWriter) Write(edge Edge) error {
+	if !tw.started {
+		tw.started = true
+		tw.writePrefixes()
+	}
+
+	switch {
+	case tw.subject != nil && bytes.Equal(edge.subject, tw.subject) && bytes.Equal(edge.predicate, tw.predicate):
+		_, _ = tw.w.WriteString(" ,\n\t\t")
+	case tw.subject != nil && bytes.Equal(edge.subject, tw.subject):
+		_, _ = tw.w.WriteString(" ;\n\t")
+		tw.writePredicate(edge.predicate)
+		_ = tw.w.WriteByte(' ')
+	default:
+		if tw.subject != nil {
+			_, _ = tw.w.WriteString(" .\n")
+		}
+
+		tw.writeResource(edge.subject)
+		_ = tw.w.WriteByte(' ')
+		tw.writePredicate(edge.predicate)
+		_ = tw.w.WriteByte(' ')
+	}
+
+	tw.subject, tw.predicate = edge.subject, edge.predicate
+	tw.writeObject(edge.object)
+
+	// bufio.Writer keeps the first error it hits, and an empty write returns it
+	_, e := tw.w.WriteString("")
+	return e
+}
+
+// Close ends the last statement and flushes the output. It doesn't close the underlying writer.
+func (tw *TurtleWriter) Close() error {
+	if !tw.started {
+		tw.started = true
+		tw.writePrefixes()
+	}
+
+	if tw.subject != nil {
+		_, _ = tw.w.WriteString(" .\n")
+		tw.subject, tw.predicate = nil, nil
+	}
+
+	return tw.w.Flush()
+}
+
+func (tw *TurtleWriter) writePrefixes() {
+	prefixes := append([]string{}, tw.namespaces...)
+	sort.Strings(prefixes)
+
+	for _, prefix := range prefixes {
+		_, _ = tw.w.WriteString("@prefix " + prefix + ": ")
+		writeIRI(tw.w, []byte(tw.prefixes[prefix]))
+		_, _ = tw.w.WriteString(" .\n")
+	}
+
+	if len(prefixes) > 0 {
+		_ = tw.w.WriteByte('\n')
+	}
+}
+
+func (tw *TurtleWriter) writePredicate(predicate []byte) {
+	if string(predicate) == rdfType {
+		_ = tw.w.WriteByte('a')
+		return
+	}
+
+	tw.writeResource(predicate)
+}
+
+func (tw *TurtleWriter) writeResource(resource []byte) {
+	if name, ok := tw.prefixedName(string(resource)); ok {
+		_, _ = tw.w.WriteString(name)
+		return
+	}
+
+	writeIRI(tw.w, resource)
+}
+
+// prefixedName shortens an IRI with the longest namespace it starts with, if what's left is a simple local name
+func (tw *TurtleWriter) prefixedName(iri string) (string, bool) {
+	for _, prefix := range tw.namespaces {
+		namespace := tw.prefixes[prefix]
+
+		if !strings.HasPrefix(iri, namespace) {
+			continue
+		}
+
+		local := strings.TrimPrefix(iri, namespace)
+		simple := local != "" && !strings.HasSuffix(local, ".") && !strings.HasPrefix(local, "-") &&
+			!strings.HasPrefix(local, ".")
+
+		for _, r := range local {
+			simple = simple && isNameRune(r)
+		}
+
+		if simple {
+			return prefix + ":" + local, true
+		}
+	}
+
+	return "", false
+}
+
+func (tw *TurtleWriter) writeObject(object interface{}) {
+	// integers and booleans can be written bare
+	switch v := object.(type) {
+	case []byte:
+		tw.writeResource(v)
+		return
+	case int64, bool:
+		lexical, _ := literalForm(object)
+		_, _ = tw.w.WriteString(lexical)
+		return
+	}
+
+	writeObject(tw.w, object, func(iri string) {
+		tw.writeResource([]byte(iri))
+	})
+}
+
+// ImportTurtle adds the edges in a Turtle document to the graph, in batches like ImportCSV. With DryRun set, it
+// only checks the document.
+func (graph *SimpleGraph) ImportTurtle(ctx context.Context, r io.Reader, options RDFOptions) (*ImportReport, error) {
+	reader := NewTurtleReader(r)
+
+	return graph.importEdges(ctx, reader.Read, reader.parser.linesRead, options)
+}
+
+// ExportTurtle writes the edges matching the query as Turtle, with the given prefixes, and returns how many it wrote.
+// Edges are written in the order of the index the query reads, so a query with a fixed subject, or none of its fields
+// fixed, groups them best.
+func (graph *SimpleGraph) ExportTurtle(ctx context.Context, w io.Writer, query Query, prefixes map[string]string) (int, error) {
+	writer := NewTurtleWriter(w, prefixes)
+	written, e := graph.exportEdges(ctx, query, writer.Write)
+
+	if e != nil {
+		return written, e
+	}
+
+	return written, writer.Close()
+}
@@ -0,0 +1,118 @@
+package simplegraph
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"math"
+	"time"
+)
+
+// An edge's object can be []byte, string, int64 (or int), float64, bool, time.Time or tuple.UUID. Each is stored with
+// the tuple layer's encoding for its type, so indices sort objects of one type by value: numbers numerically and
+// timestamps chronologically. Objects of different types never compare equal, and sort by type first.
+//
+// The tuple layer has no timestamp type, so a time.Time is stored as a nested tuple holding its Unix time in
+// nanoseconds, and comes back in UTC. That limits times to the years 1678 to 2262. An int comes back as an int64, and
+// a nil object as an empty []byte.
+
+// minTime and maxTime are the earliest and latest times whose Unix time in nanoseconds fits in an int64
+var (
+	minTime = time.Unix(0, math.MinInt64)
+	maxTime = time.Unix(0, math.MaxInt64)
+)
+
+// validateValue checks that an object can be stored
+func validateValue(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		if v.Before(minTime) || v.After(maxTime) {
+			return fmt.Errorf("time %v is outside the range that can be stored, %v to %v",
+				formatValue(v), formatValue(minTime.UTC()), formatValue(maxTime.UTC()))
+		}
+
+		return nil
+	case nil, []byte, string, int, int64, float64, bool, tuple.UUID:
+		return nil
+	default:
+		return fmt.Errorf("unsupported object type %T; use []byte, string, int64, float64, bool, time.Time or tuple.UUID", value)
+	}
+}
+
+// encodeValue converts an object to the tuple element it's stored as
+func encodeValue(value interface{}) tuple.TupleElement {
+	switch v := value.(type) {
+	case nil:
+		return []byte{}
+	case int:
+		return int64(v)
+	case time.Time:
+		return tuple.Tuple{ v.UnixNano() }
+	default:
+		return v
+	}
+}
+
+// decodeValue converts a stored tuple element back to the object it encodes
+func decodeValue(element tuple.TupleElement) (interface{}, error) {
+	switch v := element.(type) {
+	case []byte, string, int64, float64, bool, tuple.UUID:
+		return v, nil
+	case tuple.Tuple:
+		if len(v) == 1 {
+			if nanos, ok := v[0].(int64); ok {
+				return time.Unix(0, nanos).UTC(), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported stored object %#v", element)
+}
+
+// compareValues orders values the way the indices do
+func compareValues(a, b interface{}) int {
+	if aBytes, ok := a.([]byte); ok {
+		if bBytes, ok := b.([]byte); ok {
+			return bytes.Compare(aBytes, bBytes)
+		}
+	}
+
+	return bytes.Compare(tuple.Tuple{ encodeValue(a) }.Pack(), tuple.Tuple{ encodeValue(b) }.Pack())
+}
+
+func valuesEqual(a, b interface{}) bool {
+	return compareValues(a, b) == 0
+}
+
+// formatValue renders a value for people: quoted if it's text, as Go would print it otherwise
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte, string:
+		return fmt.Sprintf("%q", v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// fieldValue converts a subject or predicate to a value, keeping nil as an untyped nil
+func fieldValue(value []byte) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return value
+}
+
+// valueSize roughly estimates the memory a value holds
+func valueSize(value interface{}) int {
+	switch v := value.(type) {
+	case []byte:
+		return len(v)
+	case string:
+		return len(v)
+	default:
+		return 16
+	}
+}
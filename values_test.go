@@ -0,0 +1,157 @@
+package simplegraph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+)
+
+func TestSimpleGraph_TypedObjects(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+	player := []byte("larry bird")
+	drafted := time.Date(1978, time.June, 9, 0, 0, 0, 0, time.UTC)
+	id := tuple.UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	objects := map[string]interface{}{
+		"bytes":     []byte("celtics"),
+		"string":    "forward",
+		"int64":     int64(-21791),
+		"float64":   24.3,
+		"bool":      true,
+		"timestamp": drafted,
+		"uuid":      id,
+	}
+
+	var edges []Edge
+	for predicate, object := range objects {
+		edges = append(edges, NewEdge(player, []byte(predicate), object))
+	}
+
+	if e := graph.AddEdges(edges); e != nil {
+		t.Fatal(e)
+	}
+
+	for name, idx := range Indices {
+		t.Run("it reads back each type from "+name, func(t *testing.T) {
+			got := make(map[string]interface{})
+
+			e := graph.scanIndex(context.Background(), idx, func(edge *Edge) {
+				got[string(edge.predicate)] = edge.Object()
+			})
+
+			if e != nil {
+				t.Fatal(e)
+			}
+
+			if !reflect.DeepEqual(got, objects) {
+				t.Errorf("got %v, want %v", got, objects)
+			}
+		})
+	}
+
+	t.Run("it matches a typed object exactly", func(t *testing.T) {
+		stream, _ := graph.Search(context.Background(),
+			NewQuery().WithSubjectVariable("x").WithPredicate([]byte("timestamp")).WithObject(drafted))
+
+		var got []interface{}
+		for result := range stream.Results() {
+			got = append(got, result.Edge().Object())
+		}
+
+		if want := []interface{}{drafted}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Search() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("it rejects unsupported types", func(t *testing.T) {
+		if e := graph.AddEdges([]Edge{NewEdge(player, []byte("height"), uint8(81))}); e == nil {
+			t.Errorf("expected an error adding a uint8 object")
+		}
+
+		if _, e := graph.Match(context.Background(), NewQuery().WithSubjectVariable("x").WithObject(uint8(81))); e == nil {
+			t.Errorf("expected an error matching a uint8 object")
+		}
+	})
+}
+
+func TestSimpleGraph_TypedObjectsSortByValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+	}{
+		{"ints", []interface{}{int64(-300), int64(-2), int64(0), int64(7), int64(1000)}},
+		{"floats", []interface{}{-1.5, 0.0, 0.25, 2.0, 1e10}},
+		{"timestamps", []interface{}{
+			time.Date(1956, time.March, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(1979, time.October, 12, 0, 0, 0, 0, time.UTC),
+			time.Date(1992, time.August, 18, 12, 30, 0, 5, time.UTC),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := NewSimpleGraph(NewMemoryGraph())
+
+			var edges []Edge
+			for i := len(tt.values) - 1; i >= 0; i-- {
+				edges = append(edges, NewEdge([]byte{byte(i)}, []byte("stat"), tt.values[i]))
+			}
+			_ = graph.AddEdges(edges)
+
+			var got []interface{}
+			_ = graph.scanIndex(context.Background(), Indices["pos"], func(edge *Edge) {
+				got = append(got, edge.Object())
+			})
+
+			if !reflect.DeepEqual(got, tt.values) {
+				t.Errorf("pos order = %v, want %v", got, tt.values)
+			}
+		})
+	}
+}
+
+func TestSimpleGraph_TimesOutOfRange(t *testing.T) {
+	graph := NewSimpleGraph(NewMemoryGraph())
+
+	for _, when := range []time.Time{
+		time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		minTime.Add(-time.Nanosecond),
+		maxTime.Add(time.Nanosecond),
+	} {
+		if e := graph.AddEdges([]Edge{ NewEdge([]byte("a"), []byte("at"), when) }); e == nil {
+			t.Errorf("expected %v to be rejected", when)
+		}
+	}
+
+	// the limits themselves survive a round trip
+	for _, when := range []time.Time{ minTime.UTC(), maxTime.UTC() } {
+		if e := graph.AddEdges([]Edge{ NewEdge([]byte("a"), []byte("at"), when) }); e != nil {
+			t.Fatal(e)
+		}
+
+		if got := queriedEdges(t, graph, NewQuery().WithObject(when)); len(got) != 1 || !got[0].Object().(time.Time).Equal(when) {
+			t.Errorf("got %v, want %v", got, when)
+		}
+	}
+}
+
+func Test_encodeValue(t *testing.T) {
+	if got := encodeValue(7); got != int64(7) {
+		t.Errorf("encodeValue(int) = %#v, want int64", got)
+	}
+
+	if value, e := decodeValue(encodeValue(7)); e != nil || value != int64(7) {
+		t.Errorf("decodeValue() = %#v, %v, want int64(7)", value, e)
+	}
+
+	if value, e := decodeValue(encodeValue(nil)); e != nil || !reflect.DeepEqual(value, []byte{}) {
+		t.Errorf("decodeValue() = %#v, %v, want an empty []byte for a nil object", value, e)
+	}
+
+	if _, e := decodeValue(tuple.Tuple{"not", "a", "time"}); e == nil {
+		t.Errorf("expected an error decoding an unknown nested tuple")
+	}
+}
@@ -0,0 +1,107 @@
+package simplegraph
+
+import "context"
+
+// VariableHashJoin joins two streams of SearchResults on the variables they share, without needing either to be
+// sorted. It reads from both inputs in turn until one of them is exhausted, builds a table from that one, the
+// smaller, and probes it with the rest of the other. Only the smaller input is ever held in full, and at most as many
+// results again from the larger, so neither side has to be buffered and re-sorted the way a merge join would need.
+// Output is in no particular order.
+type VariableHashJoin struct {
+	variables []string
+	metrics *OperatorMetrics
+}
+
+func (hj *VariableHashJoin) join(ctx context.Context, left, right <-chan *SearchResults) <-chan *SearchResults {
+	output := make(chan *SearchResults)
+	joinOrder := TripleOrder{ variableOrder: hj.variables }
+
+	go func(output chan<- *SearchResults) {
+		defer close(output)
+
+		var leftBuffer, rightBuffer []*SearchResults
+		leftOpen, rightOpen := true, true
+
+		for leftOpen && rightOpen {
+			select {
+			case result, more := <-left:
+				if leftOpen = more; more {
+					leftBuffer = append(leftBuffer, result)
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			if !leftOpen {
+				break
+			}
+
+			select {
+			case result, more := <-right:
+				if rightOpen = more; more {
+					rightBuffer = append(rightBuffer, result)
+				}
+			case <-ctx.Done():
+				return
+			}
+
+			hj.metrics.buffered(len(leftBuffer) + len(rightBuffer), 0)
+		}
+
+		build, probeBuffer, probe, buildIsLeft := leftBuffer, rightBuffer, right, true
+
+		if leftOpen {
+			build, probeBuffer, probe, buildIsLeft = rightBuffer, leftBuffer, left, false
+		}
+
+		table := make(map[string][]*SearchResults)
+
+		for _, result := range build {
+			key := string(joinOrder.fromVariables(result.variables))
+			table[key] = append(table[key], result)
+		}
+
+		emit := func(probeResult *SearchResults) bool {
+			for _, buildResult := range table[string(joinOrder.fromVariables(probeResult.variables))] {
+				var merged *SearchResults
+
+				// keep edges in the order the patterns were joined, whichever side was built
+				if buildIsLeft {
+					merged = buildResult.merge(probeResult)
+				} else {
+					merged = probeResult.merge(buildResult)
+				}
+
+				if merged == nil {
+					continue
+				}
+
+				select {
+				case output <- merged:
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			return true
+		}
+
+		for _, result := range probeBuffer {
+			if !emit(result) {
+				return
+			}
+		}
+
+		if len(table) == 0 {
+			return
+		}
+
+		for result := range probe {
+			if !emit(result) {
+				return
+			}
+		}
+	}(output)
+
+	return output
+}
@@ -0,0 +1,69 @@
+package simplegraph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestVariableHashJoin_join(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables []string
+		left      []map[string]string
+		right     []map[string]string
+		want      []string
+	}{
+		{
+			name:      "it joins unsorted inputs on a shared variable",
+			variables: []string{"y"},
+			left:      []map[string]string{{"x": "paul", "y": "bob"}, {"x": "paul", "y": "anne"}, {"x": "jess", "y": "anne"}},
+			right:     []map[string]string{{"y": "carl", "z": "initech"}, {"y": "anne", "z": "acme"}},
+			want:      []string{"map[x:jess y:anne z:acme]", "map[x:paul y:anne z:acme]"},
+		},
+		{
+			name:      "it builds on the left when the left is smaller",
+			variables: []string{"y"},
+			left:      []map[string]string{{"x": "a", "y": "2"}},
+			right:     []map[string]string{{"y": "1", "z": "p"}, {"y": "2", "z": "q"}, {"y": "2", "z": "r"}, {"y": "3", "z": "s"}},
+			want:      []string{"map[x:a y:2 z:q]", "map[x:a y:2 z:r]"},
+		},
+		{
+			name:      "it checks shared variables it doesn't join on",
+			variables: []string{"x"},
+			left:      []map[string]string{{"x": "a", "y": "1"}, {"x": "a", "y": "2"}},
+			right:     []map[string]string{{"x": "a", "y": "2"}},
+			want:      []string{"map[x:a y:2]"},
+		},
+		{
+			name:      "it finds nothing when one side is empty",
+			variables: []string{"x"},
+			left:      []map[string]string{{"x": "a"}},
+			right:     nil,
+			want:      nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			join := VariableHashJoin{variables: tt.variables}
+
+			var got []string
+			for result := range join.join(context.Background(), searchResultsChannel(tt.left...), searchResultsChannel(tt.right...)) {
+				bindings := make(map[string]string)
+
+				for name, value := range result.Bindings() {
+					bindings[name] = string(value.([]byte))
+				}
+
+				got = append(got, fmt.Sprint(bindings))
+			}
+
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VariableHashJoin.join() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,44 +1,62 @@
 package simplegraph
 
+import (
+	"context"
+)
+
 type VariableStream struct {
 	variables map[DataField]string
 }
 
-func (ss *VariableStream) join(input <-chan *Edge) <-chan *SearchResults {
+func (ss *VariableStream) join(ctx context.Context, input <-chan *Edge) <-chan *SearchResults {
 	output := make(chan *SearchResults)
 
 	go func(output chan<- *SearchResults) {
 		defer close(output)
 		for edge := range input {
-			output <- ss.toSearchResult(edge)
+			result := ss.toSearchResult(edge)
+
+			if result == nil {
+				continue
+			}
+
+			select {
+			case output <- result:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}(output)
 
 	return output
 }
 
+// toSearchResult binds the stream's variables to the fields of the edge. A pattern like (?x, knows, ?x) uses one
+// variable for two fields, so edges whose fields disagree don't match and come back nil.
 func (ss *VariableStream) toSearchResult(edge *Edge) *SearchResults {
-	results := make(map[DataField]*VariableResult)
+	results := make(map[string]*VariableResult)
 
 	for elementType, variableName := range ss.variables {
+		var value interface{}
+
 		switch elementType {
 		case SUBJECT:
-			results[SUBJECT] = &VariableResult{
-				name: variableName,
-				value: edge.subject,
-			}
+			value = edge.subject
 		case PREDICATE:
-			results[PREDICATE] = &VariableResult{
-				name:  variableName,
-				value: edge.predicate,
-			}
+			value = edge.predicate
 		case OBJECT:
-			results[OBJECT] = &VariableResult{
-				name: variableName,
-				value: edge.object,
-			}
+			value = edge.object
+		}
+
+		if existing, ok := results[variableName]; ok && !valuesEqual(existing.value, value) {
+			return nil
+		}
+
+		results[variableName] = &VariableResult{
+			name:  variableName,
+			value: value,
 		}
 	}
 
-	return &SearchResults{ edge: edge, variables: results }
+	return &SearchResults{ edges: []*Edge{ edge }, variables: results }
 }
@@ -1,6 +1,7 @@
 package simplegraph
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -26,16 +27,16 @@ func TestSearchStream_join(t *testing.T) {
 		close(edgeChannel)
 	}()
 
-	output := searchStream.join(edgeChannel)
+	output := searchStream.join(context.Background(), edgeChannel)
 
 	count := 0
 	for variables := range output {
 		count++
-		fmt.Printf("got %v\n", *variables.variables[SUBJECT])
+		fmt.Printf("got %v\n", *variables.variables["x"])
 		if !reflect.DeepEqual(*variables, SearchResults{
-			edge: &edge,
-			variables: map[DataField]*VariableResult{
-				SUBJECT: {
+			edges: []*Edge{&edge},
+			variables: map[string]*VariableResult{
+				"x": {
 					name:  "x",
 					value: []byte("S"),
 				},
@@ -49,3 +50,34 @@ func TestSearchStream_join(t *testing.T) {
 		t.Fatalf("got %v rows", count)
 	}
 }
+
+func TestSearchResults_Get(t *testing.T) {
+	query := NewQuery().WithSubjectVariable("x").WithObjectVariable("y")
+	searchStream := VariableStream{
+		variables: query.toVariableMap(),
+	}
+
+	edge := NewEdge([]byte("S"), []byte("P"), []byte("O"))
+	result := searchStream.toSearchResult(&edge)
+
+	if value, ok := result.Get("x"); !ok || string(value.([]byte)) != "S" {
+		t.Errorf("Get(x) = %s, %v", value, ok)
+	}
+
+	if value, ok := result.Get("y"); !ok || string(value.([]byte)) != "O" {
+		t.Errorf("Get(y) = %s, %v", value, ok)
+	}
+
+	if _, ok := result.Get("z"); ok {
+		t.Errorf("Get(z) should not be bound")
+	}
+
+	want := map[string]interface{}{"x": []byte("S"), "y": []byte("O")}
+	if got := result.Bindings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Bindings() = %v, want %v", got, want)
+	}
+
+	if result.Edge() != &edge {
+		t.Errorf("Edge() = %v, want %v", result.Edge(), &edge)
+	}
+}
@@ -0,0 +1,90 @@
+package simplegraph
+
+import (
+	"bytes"
+	"context"
+)
+
+// VariableStreamJoin is a merge join of two streams of SearchResults on the variables they share. Both inputs must be
+// sorted by variables, in that order, e.g. (?x friend ?y) from pos and (?y worksAt ?z) from pso both sort on ?y
+// even though it sits in a different position in each pattern. Each pair of results with equal keys is emitted
+// once, with their bindings merged.
+type VariableStreamJoin struct {
+	variables []string
+	metrics *OperatorMetrics
+}
+
+func (vj *VariableStreamJoin) join(ctx context.Context, left, right <-chan *SearchResults) <-chan *SearchResults {
+	output := make(chan *SearchResults)
+	joinOrder := TripleOrder{ variableOrder: vj.variables }
+
+	go func(output chan<- *SearchResults) {
+		defer close(output)
+
+		var next *SearchResults
+		var nextKey []byte
+
+		advance := func() {
+			var more bool
+
+			if next, more = <-right; more {
+				nextKey = joinOrder.fromVariables(next.variables)
+			} else {
+				next = nil
+			}
+		}
+
+		advance()
+
+		// the run of results on the right sharing groupKey. keys can repeat on both sides, so the run is buffered
+		// to be paired with every result on the left that has the same key
+		var group []*SearchResults
+		var groupKey []byte
+
+		for leftResult := range left {
+			leftKey := joinOrder.fromVariables(leftResult.variables)
+
+			if group == nil || !bytes.Equal(leftKey, groupKey) {
+				group = nil
+
+				// seek the right side forward to the first key that could match
+				for next != nil && bytes.Compare(nextKey, leftKey) < 0 {
+					advance()
+				}
+
+				if next == nil {
+					return
+				}
+
+				if !bytes.Equal(nextKey, leftKey) {
+					continue
+				}
+
+				groupKey = nextKey
+				for next != nil && bytes.Equal(nextKey, groupKey) {
+					group = append(group, next)
+					advance()
+				}
+
+				vj.metrics.buffered(len(group), 0)
+			}
+
+			for _, rightResult := range group {
+				// variables shared by both sides but not joined on still have to agree
+				merged := leftResult.merge(rightResult)
+
+				if merged == nil {
+					continue
+				}
+
+				select {
+				case output <- merged:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}(output)
+
+	return output
+}
@@ -0,0 +1,127 @@
+package simplegraph
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func searchResultsChannel(results ...map[string]string) <-chan *SearchResults {
+	channel := make(chan *SearchResults)
+
+	go func() {
+		defer close(channel)
+
+		for _, bindings := range results {
+			variables := make(map[string]*VariableResult)
+
+			for name, value := range bindings {
+				variables[name] = &VariableResult{name: name, value: []byte(value)}
+			}
+
+			channel <- &SearchResults{variables: variables}
+		}
+	}()
+
+	return channel
+}
+
+func TestVariableStreamJoin_join(t *testing.T) {
+	tests := []struct {
+		name      string
+		variables []string
+		left      []map[string]string
+		right     []map[string]string
+		want      []map[string]string
+	}{
+		{
+			name:      "it joins on a variable in different positions",
+			variables: []string{"y"},
+			left:      []map[string]string{{"x": "paul", "y": "anne"}, {"x": "paul", "y": "bob"}},
+			right:     []map[string]string{{"y": "anne", "z": "acme"}, {"y": "carl", "z": "initech"}},
+			want:      []map[string]string{{"x": "paul", "y": "anne", "z": "acme"}},
+		},
+		{
+			name:      "it pairs duplicate keys on both sides",
+			variables: []string{"y"},
+			left:      []map[string]string{{"x": "a", "y": "1"}, {"x": "b", "y": "1"}, {"x": "c", "y": "2"}},
+			right:     []map[string]string{{"y": "1", "z": "p"}, {"y": "1", "z": "q"}, {"y": "2", "z": "r"}, {"y": "2", "z": "s"}},
+			want: []map[string]string{
+				{"x": "a", "y": "1", "z": "p"}, {"x": "a", "y": "1", "z": "q"},
+				{"x": "b", "y": "1", "z": "p"}, {"x": "b", "y": "1", "z": "q"},
+				{"x": "c", "y": "2", "z": "r"}, {"x": "c", "y": "2", "z": "s"},
+			},
+		},
+		{
+			name:      "it checks shared variables it doesn't join on",
+			variables: []string{"x"},
+			left:      []map[string]string{{"x": "a", "y": "1"}, {"x": "a", "y": "2"}},
+			right:     []map[string]string{{"x": "a", "y": "2"}},
+			want:      []map[string]string{{"x": "a", "y": "2"}},
+		},
+		{
+			name:      "it joins on several variables",
+			variables: []string{"x", "y"},
+			left:      []map[string]string{{"x": "a", "y": "1"}, {"x": "a", "y": "2"}, {"x": "b", "y": "1"}},
+			right:     []map[string]string{{"x": "a", "y": "2", "z": "p"}, {"x": "b", "y": "1", "z": "q"}},
+			want:      []map[string]string{{"x": "a", "y": "2", "z": "p"}, {"x": "b", "y": "1", "z": "q"}},
+		},
+		{
+			name:      "it takes the product without join variables",
+			variables: nil,
+			left:      []map[string]string{{"x": "a"}, {"x": "b"}},
+			right:     []map[string]string{{"y": "1"}, {"y": "2"}},
+			want:      []map[string]string{{"x": "a", "y": "1"}, {"x": "a", "y": "2"}, {"x": "b", "y": "1"}, {"x": "b", "y": "2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			join := VariableStreamJoin{variables: tt.variables}
+
+			var got []map[string]string
+			for result := range join.join(context.Background(), searchResultsChannel(tt.left...), searchResultsChannel(tt.right...)) {
+				bindings := make(map[string]string)
+
+				for name, value := range result.Bindings() {
+					bindings[name] = string(value.([]byte))
+				}
+
+				got = append(got, bindings)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VariableStreamJoin.join() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderedStreamJoin_duplicates(t *testing.T) {
+	edges := func(objects ...string) <-chan *Edge {
+		channel := make(chan *Edge)
+
+		go func() {
+			defer close(channel)
+
+			for _, object := range objects {
+				edge := NewEdge([]byte("S"), []byte("P"), []byte(object))
+				channel <- &edge
+			}
+		}()
+
+		return channel
+	}
+
+	join := OrderedStreamJoin{tripleOrder: TripleOrder{dataFieldOrder: []DataField{OBJECT}}}
+
+	var got []string
+	for edge := range join.join(context.Background(), edges("a", "a", "b", "c", "c"), edges("a", "c", "c")) {
+		got = append(got, string(edge.object.([]byte)))
+	}
+
+	sort.Strings(got)
+	if want := []string{"a", "a", "c", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedStreamJoin.join() = %v, want %v", got, want)
+	}
+}